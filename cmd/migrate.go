@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/webhook"
+	"github.com/spf13/cobra"
+)
+
+var inPlace bool
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [file...]",
+	Short: "Migrates sidecar definitions to the latest schema version",
+	Long: fmt.Sprintf("Migrates sidecar definitions of any schema version to %s, reading the standard input when no file is given.",
+		webhook.LatestSidecarAPIVersion),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			if inPlace {
+				return fmt.Errorf("--inPlace requires files")
+			}
+			content, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			return migrate(content, cmd.OutOrStdout())
+		}
+		for _, file := range args {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if !inPlace {
+				if err := migrate(content, cmd.OutOrStdout()); err != nil {
+					return fmt.Errorf("%s: %v", file, err)
+				}
+				continue
+			}
+			migrated, err := webhook.MigrateSidecars(content)
+			if err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+			if err := os.WriteFile(file, migrated, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func migrate(content []byte, out io.Writer) error {
+	migrated, err := webhook.MigrateSidecars(content)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "---\n%s", migrated)
+	return err
+}
+
+func init() {
+	migrateCmd.Flags().BoolVarP(&inPlace, "inPlace", "i", false, "Rewrite the files instead of printing them")
+	rootCmd.AddCommand(migrateCmd)
+}
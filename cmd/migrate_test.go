@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_migrateCmd(t *testing.T) {
+	const (
+		legacy   = "- name: envoy\n  version: 1.0.0\n"
+		migrated = "apiVersion: sidecar-injector.expedia.com/v2\nsidecars:\n- name: envoy\n  version: 1.0.0\n"
+	)
+	tests := []struct {
+		name      string
+		stdin     string
+		files     [][2]string
+		inPlace   bool
+		want      string
+		wantFiles map[string]string
+		wantErr   assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "standard input",
+			stdin:   legacy,
+			want:    "---\n" + migrated,
+			wantErr: assert.NoError,
+		},
+		{
+			name:      "files",
+			files:     [][2]string{{"a.yaml", legacy}, {"b.yaml", migrated}},
+			want:      "---\n" + migrated + "---\n" + migrated,
+			wantFiles: map[string]string{"a.yaml": legacy, "b.yaml": migrated},
+			wantErr:   assert.NoError,
+		},
+		{
+			name:      "in place",
+			files:     [][2]string{{"a.yaml", legacy}, {"b.yaml", migrated}},
+			inPlace:   true,
+			wantFiles: map[string]string{"a.yaml": migrated, "b.yaml": migrated},
+			wantErr:   assert.NoError,
+		},
+		{
+			name:    "in place without files",
+			stdin:   legacy,
+			inPlace: true,
+			wantErr: assert.Error,
+		},
+		{
+			name:      "unknown version",
+			files:     [][2]string{{"a.yaml", "apiVersion: v0\n"}},
+			inPlace:   true,
+			wantFiles: map[string]string{"a.yaml": "apiVersion: v0\n"},
+			wantErr:   assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var args []string
+			for _, file := range tt.files {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, file[0]), []byte(file[1]), 0644))
+				args = append(args, filepath.Join(dir, file[0]))
+			}
+			inPlace = tt.inPlace
+			defer func() { inPlace = false }()
+			out := &bytes.Buffer{}
+			migrateCmd.SetIn(strings.NewReader(tt.stdin))
+			migrateCmd.SetOut(out)
+			err := migrateCmd.RunE(migrateCmd, args)
+			if !tt.wantErr(t, err, "migrate %v", args) {
+				return
+			}
+			assert.Equal(t, tt.want, out.String())
+			for name, want := range tt.wantFiles {
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				assert.NoError(t, err)
+				assert.Equalf(t, want, string(got), "content of %s", name)
+			}
+		})
+	}
+}
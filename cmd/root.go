@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/httpd"
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/webhook"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -11,6 +14,7 @@ import (
 var (
 	httpdConf httpd.SimpleServer
 	debug     bool
+	logFormat string
 )
 
 var rootCmd = &cobra.Command{
@@ -20,11 +24,28 @@ var rootCmd = &cobra.Command{
 		if debug {
 			log.SetLevel(log.DebugLevel)
 		}
+		formatter, err := logFormatter(logFormat)
+		if err != nil {
+			return err
+		}
+		log.SetFormatter(formatter)
 		log.Infof("SimpleServer starting to listen in port %v", httpdConf.Port)
 		return httpdConf.Start()
 	},
 }
 
+// logFormatter Formatter of the logs in the given format
+func logFormatter(format string) (log.Formatter, error) {
+	switch format {
+	case "text":
+		return &log.TextFormatter{}, nil
+	case "json":
+		return &log.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %s, expected text or json", format)
+	}
+}
+
 // Execute Kicks off the application
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -41,6 +62,34 @@ func init() {
 	rootCmd.Flags().BoolVar(&httpdConf.Local, "local", false, "Local run mode")
 	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).InjectPrefix, "injectPrefix", "sidecar-injector.expedia.com", "Injector Prefix")
 	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).InjectName, "injectName", "inject", "Injector Name")
-	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).SidecarDataKey, "sidecarDataKey", "sidecars.yaml", "ConfigMap Sidecar Data Key")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).SidecarDataKey, "sidecarDataKey", "sidecars.yaml", "ConfigMap Sidecar Data Key, or a pattern like *.sidecars.yaml matching several keys")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).PodSecurityAction, "podSecurityAction", webhook.PodSecurityActionNone, "Action for sidecars violating the namespace Pod Security Standards: none, deny or skip")
+	rootCmd.Flags().StringToStringVar(&(&httpdConf.Patcher).ImageRegistryRewrites, "imageRegistryRewrites", nil, "Registry prefixes of injected images to rewrite, e.g. docker.io=registry.example.com/dockerhub")
+	rootCmd.Flags().StringSliceVar(&(&httpdConf.Patcher).AllowedImageRegistries, "allowedImageRegistries", nil, "Registries or repositories injected images are allowed from, any when empty")
+	rootCmd.Flags().BoolVar(&(&httpdConf.Patcher).RequireImageDigest, "requireImageDigest", false, "Require injected images to be pinned to a digest")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).SunsetAction, "sunsetAction", webhook.SunsetActionDeny, "Action for deprecated sidecars past their sunset date: deny or replace")
+	rootCmd.Flags().Var(&(&httpdConf.Patcher).PodBudget, "podSidecarBudget", "Budget of the sidecars injected into each pod as YAML or JSON, e.g. {maxSidecars: 3, requests: {cpu: 500m}}")
+	rootCmd.Flags().BoolVar(&(&httpdConf.Patcher).PodBudgets, "podBudgets", false, "Enable pod sidecar budgets set by namespace annotations, implied by --podSidecarBudget")
+	rootCmd.Flags().Var(&(&httpdConf.Patcher).NamespaceBudget, "namespaceSidecarBudget", "Budget of the sidecars injected into all pods of a namespace as YAML or JSON")
+	rootCmd.Flags().BoolVar(&(&httpdConf.Patcher).NamespaceBudgets, "namespaceBudgets", false, "Enable namespace sidecar budgets set by namespace annotations, implied by --namespaceSidecarBudget")
+	rootCmd.Flags().DurationVar(&httpdConf.PodCacheSyncTimeout, "podCacheSyncTimeout", 2*time.Minute, "Time the pod cache of the namespace budgets is given to sync before the server fails to start")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).BudgetAction, "budgetAction", webhook.BudgetActionDeny, "Action for pods exceeding a sidecar budget: deny or warn")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).LimitRangePolicy, "limitRangePolicy", webhook.LimitRangePolicyNone, "Resources of injected containers from the namespace LimitRanges: none, clamp, min, default or injector")
+	rootCmd.Flags().Var(&(&httpdConf.Patcher).DefaultResources, "sidecarDefaultResources", "Resources set on injected containers missing them with the injector limitRangePolicy, e.g. {requests: {cpu: 10m}, limits: {memory: 128Mi}}")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).PortConflictAction, "portConflictAction", webhook.PortConflictActionWarn, "Action for sidecar ports conflicting with ports of the pod: deny or warn")
+	rootCmd.Flags().Var(&(&httpdConf.Patcher).AutoPortRange, "autoPortRange", "Range of ports assigned to sidecar auto ports, e.g. 15000-15999")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).NameCollisionPolicy, "nameCollisionPolicy", webhook.NameCollisionPolicyFail, "Policy for sidecar container and volume names defined differently in the pod: fail or rename")
+	rootCmd.Flags().StringVar(&(&httpdConf.Patcher).DefaultPlatform, "defaultPlatform", "linux/amd64", "Platform of pods not restricted to an operating system or architecture, used to select sidecar variants")
+	rootCmd.Flags().StringSliceVar(&(&httpdConf.Patcher).ExtendsNamespaces, "extendsNamespaces", nil, "Namespaces whose sidecars can be extended by sidecars of other namespaces")
+	rootCmd.Flags().StringSliceVar(&(&httpdConf.Patcher).MetricsLabels, "metricsLabels", []string{webhook.MetricsLabelNamespace, webhook.MetricsLabelSidecar}, "High cardinality labels retained on the metrics: namespace and sidecar, left empty when not retained")
+	rootCmd.Flags().BoolVar(&httpdConf.Events, "events", true, "Record Kubernetes Events for injection failures and warnings on sidecar ConfigMaps and workloads")
+	rootCmd.Flags().IntVar(&httpdConf.EventBurst, "eventBurst", 25, "Number of events recorded for an object before they are rate limited")
+	rootCmd.Flags().DurationVar(&httpdConf.EventInterval, "eventInterval", 5*time.Minute, "Interval at which a rate limited object can record one more event")
+	rootCmd.Flags().StringVar(&httpdConf.Tracing.Endpoint, "tracingEndpoint", "", "OTLP endpoint the admission spans are exported to, e.g. otel-collector:4317, tracing is disabled when empty")
+	rootCmd.Flags().StringVar(&httpdConf.Tracing.Protocol, "tracingProtocol", httpd.TracingProtocolGRPC, "OTLP protocol of the tracing endpoint: grpc or http")
+	rootCmd.Flags().BoolVar(&httpdConf.Tracing.Insecure, "tracingInsecure", false, "Export spans to the tracing endpoint without TLS")
+	rootCmd.Flags().Float64Var(&httpdConf.Tracing.SampleRatio, "tracingSampleRatio", 1, "Ratio of the admission requests traced, unless the API server propagates its own sampling decision")
+	rootCmd.Flags().StringSliceVar(&(&httpdConf.Patcher).RedactedAnnotations, "redactedAnnotations", nil, "Annotations whose values are redacted from the logged patches, besides env values and secret references")
+	rootCmd.Flags().StringVar(&logFormat, "logFormat", "text", "Format of the logs: text or json")
 	rootCmd.Flags().BoolVar(&debug, "debug", false, "enable debug logs")
 }
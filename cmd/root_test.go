@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_logFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		wantJSON bool
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{name: "text", format: "text", wantJSON: false, wantErr: assert.NoError},
+		{name: "json", format: "json", wantJSON: true, wantErr: assert.NoError},
+		{name: "unknown", format: "yaml", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := logFormatter(tt.format)
+			if !tt.wantErr(t, err, "logFormatter(%v)", tt.format) || err != nil {
+				return
+			}
+			line, err := got.Format(log.WithField("uid", "1234").WithField("pod", "app"))
+			assert.NoError(t, err)
+			var fields map[string]interface{}
+			if !tt.wantJSON {
+				assert.Error(t, json.Unmarshal(line, &fields), "logFormatter(%v) formatted JSON", tt.format)
+				assert.Contains(t, string(line), "uid=1234")
+				return
+			}
+			assert.NoError(t, json.Unmarshal(line, &fields), "logFormatter(%v) did not format JSON", tt.format)
+			assert.Equal(t, "1234", fields["uid"])
+			assert.Equal(t, "app", fields["pod"])
+		})
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"io"
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/http"
+	"time"
 )
 
 // PatchOperation JsonPatch struct http://jsonpatch.com/
@@ -33,8 +37,13 @@ type Handler struct {
 
 // HandleAdmission HttpServer function to handle Admissions
 func (handler *Handler) HandleAdmission(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+	ctx, span := tracer.Start(otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(request.Header)), "HandleAdmission")
+	defer span.End()
 	if err := validateRequest(request); err != nil {
 		log.Error(err.Error())
+		failSpan(span, err)
+		observeAdmission("", admissionOutcomeError, start)
 		handler.writeErrorAdmissionReview(http.StatusBadRequest, err.Error(), writer)
 		return
 	}
@@ -42,34 +51,51 @@ func (handler *Handler) HandleAdmission(writer http.ResponseWriter, request *htt
 	body, err := readRequestBody(request)
 	if err != nil {
 		log.Error(err.Error())
+		failSpan(span, err)
+		observeAdmission("", admissionOutcomeError, start)
 		handler.writeErrorAdmissionReview(http.StatusInternalServerError, err.Error(), writer)
 		return
 	}
 
 	admReview := admissionv1.AdmissionReview{}
 
+	_, decodeSpan := tracer.Start(ctx, "DecodeAdmissionReview")
 	err = json.Unmarshal(body, &admReview)
+	decodeSpan.End()
 	if err != nil {
 		message := fmt.Sprintf("Could not decode body: %v", err)
 		log.Error(message)
+		failSpan(span, err)
+		observeAdmission("", admissionOutcomeError, start)
 		handler.writeErrorAdmissionReview(http.StatusInternalServerError, message, writer)
 		return
 	}
 
-	ctx := context.Background()
+	ctx = WithWarnings(ctx)
 
 	req := admReview.Request
-	log.Infof("AdmissionReview for Kind=%v, Namespace=%v Name=%v UID=%v patchOperation=%v UserInfo=%v", req.Kind, req.Namespace, req.Name, req.UID, req.Operation, req.UserInfo)
+	ctx = WithLogFields(ctx, log.Fields{"uid": req.UID, "namespace": req.Namespace, "pod": requestObjectName(req)})
+	logger := Logger(ctx)
+	span.SetAttributes(semconv.K8SNamespaceName(req.Namespace), admissionUIDKey.String(string(req.UID)), admissionOperationKey.String(string(req.Operation)))
+	logger.Infof("AdmissionReview for Kind=%v, Namespace=%v Name=%v UID=%v patchOperation=%v User=%v", req.Kind, req.Namespace, req.Name, req.UID, req.Operation, req.UserInfo.Username)
 	if patchOperations, err := handler.Process(ctx, req); err != nil {
 		message := fmt.Sprintf("request for object '%s' with name '%s' in namespace '%s' denied: %v", req.Kind.String(), req.Name, req.Namespace, err)
-		log.Error(message)
-		handler.writeDeniedAdmissionResponse(&admReview, message, writer)
+		logger.Error(message)
+		failSpan(span, err)
+		observeAdmission(string(req.Operation), admissionOutcomeDenied, start)
+		handler.writeDeniedAdmissionResponse(&admReview, message, Warnings(ctx), writer)
 	} else if patchBytes, err := json.Marshal(patchOperations); err != nil {
 		message := fmt.Sprintf("request for object '%s' with name '%s' in namespace '%s' denied: %v", req.Kind.String(), req.Name, req.Namespace, err)
-		log.Error(message)
-		handler.writeDeniedAdmissionResponse(&admReview, message, writer)
+		logger.Error(message)
+		failSpan(span, err)
+		observeAdmission(string(req.Operation), admissionOutcomeDenied, start)
+		handler.writeDeniedAdmissionResponse(&admReview, message, Warnings(ctx), writer)
 	} else {
-		handler.writeAllowedAdmissionReview(&admReview, patchBytes, writer)
+		observeAdmission(string(req.Operation), admissionOutcomeAllowed, start)
+		if patchOperations != nil {
+			patchSize.Observe(float64(len(patchBytes)))
+		}
+		handler.writeAllowedAdmissionReview(&admReview, patchBytes, Warnings(ctx), writer)
 	}
 }
 
@@ -87,6 +113,25 @@ func (handler *Handler) Process(ctx context.Context, request *admissionv1.Admiss
 	}
 }
 
+// requestObjectName Name of the object of the request, its generate name when it is not named yet
+func requestObjectName(request *admissionv1.AdmissionRequest) string {
+	if request.Name != "" {
+		return request.Name
+	}
+	raw := request.Object.Raw
+	if raw == nil {
+		raw = request.OldObject.Raw
+	}
+	var object metav1.PartialObjectMetadata
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return ""
+	}
+	if object.Name != "" {
+		return object.Name
+	}
+	return object.GenerateName
+}
+
 func validateRequest(req *http.Request) error {
 	if req.Method != http.MethodPost {
 		return fmt.Errorf("wrong http verb. got %s", req.Method)
@@ -109,10 +154,11 @@ func readRequestBody(req *http.Request) ([]byte, error) {
 	return body, nil
 }
 
-func (handler *Handler) writeAllowedAdmissionReview(ar *admissionv1.AdmissionReview, patch []byte, res http.ResponseWriter) {
+func (handler *Handler) writeAllowedAdmissionReview(ar *admissionv1.AdmissionReview, patch []byte, warnings []string, res http.ResponseWriter) {
 	ar.Response = handler.admissionResponse(http.StatusOK, "")
 	ar.Response.Allowed = true
 	ar.Response.UID = ar.Request.UID
+	ar.Response.Warnings = warnings
 	if patch != nil {
 		pt := admissionv1.PatchTypeJSONPatch
 		ar.Response.Patch = patch
@@ -121,9 +167,10 @@ func (handler *Handler) writeAllowedAdmissionReview(ar *admissionv1.AdmissionRev
 	handler.write(ar, res)
 }
 
-func (handler *Handler) writeDeniedAdmissionResponse(ar *admissionv1.AdmissionReview, message string, res http.ResponseWriter) {
+func (handler *Handler) writeDeniedAdmissionResponse(ar *admissionv1.AdmissionReview, message string, warnings []string, res http.ResponseWriter) {
 	ar.Response = handler.admissionResponse(http.StatusForbidden, message)
 	ar.Response.UID = ar.Request.UID
+	ar.Response.Warnings = warnings
 	handler.write(ar, res)
 }
 
@@ -0,0 +1,22 @@
+package admission
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// WithLogFields Returns a context whose logger adds the fields to every line logged for the request
+func WithLogFields(ctx context.Context, fields log.Fields) context.Context {
+	return context.WithValue(ctx, loggerKey{}, Logger(ctx).WithFields(fields))
+}
+
+// Logger Returns the logger of the request, the standard logger when the context has none
+func Logger(ctx context.Context) *log.Entry {
+	if logger, ok := ctx.Value(loggerKey{}).(*log.Entry); ok {
+		return logger
+	}
+	return log.NewEntry(log.StandardLogger())
+}
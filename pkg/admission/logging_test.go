@@ -0,0 +1,99 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWithLogFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []log.Fields
+		want   log.Fields
+	}{
+		{name: "no fields", want: log.Fields{}},
+		{name: "fields", fields: []log.Fields{{"uid": "1234"}}, want: log.Fields{"uid": "1234"}},
+		{
+			name:   "nested fields",
+			fields: []log.Fields{{"uid": "1234", "pod": "app-"}, {"pod": "app", "sidecar": "envoy"}},
+			want:   log.Fields{"uid": "1234", "pod": "app", "sidecar": "envoy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, fields := range tt.fields {
+				ctx = WithLogFields(ctx, fields)
+			}
+			assert.Equalf(t, tt.want, Logger(ctx).Data, "Logger(%v)", tt.fields)
+		})
+	}
+}
+
+func TestHandler_HandleAdmissionLogFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		pod     corev1.Pod
+		podName string
+		err     error
+		want    log.Fields
+	}{
+		{
+			name:    "named pod",
+			pod:     corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app"}},
+			podName: "app",
+			want:    log.Fields{"uid": types.UID("1234"), "namespace": "test", "pod": "app"},
+		},
+		{
+			name: "generated pod name",
+			pod:  corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "app-5d8f-"}},
+			err:  errors.New("denied"),
+			want: log.Fields{"uid": types.UID("1234"), "namespace": "test", "pod": "app-5d8f-"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := test.NewGlobal()
+			defer log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+			raw, err := json.Marshal(tt.pod)
+			assert.NoError(t, err)
+			body, err := json.Marshal(admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "1234",
+					Namespace: "test",
+					Name:      tt.podName,
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:replicaset-controller", Groups: []string{"system:serviceaccounts"}},
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+			assert.NoError(t, err)
+			handler := &Handler{Handler: &PodAdmissionRequestHandler{PodHandler: fakePodPatcher{err: tt.err}}}
+			request := httptest.NewRequest(http.MethodPost, "/mutate", strings.NewReader(string(body)))
+			request.Header.Set("Content-Type", "application/json")
+			handler.HandleAdmission(httptest.NewRecorder(), request)
+
+			assert.NotEmpty(t, hook.AllEntries())
+			for _, entry := range hook.AllEntries() {
+				assert.Equalf(t, tt.want, entry.Data, "fields of %q", entry.Message)
+				assert.NotContainsf(t, entry.Message, "system:serviceaccounts", "groups logged in %q", entry.Message)
+			}
+		})
+	}
+}
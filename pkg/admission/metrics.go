@@ -0,0 +1,42 @@
+package admission
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "sidecar_injector"
+
+const (
+	admissionOutcomeAllowed = "allowed"
+	admissionOutcomeDenied  = "denied"
+	admissionOutcomeError   = "error"
+)
+
+var admissionRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "admission_requests_total",
+	Help:      "Number of admission requests by operation and outcome: allowed, denied or error.",
+}, []string{"operation", "outcome"})
+
+var admissionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "admission_duration_seconds",
+	Help:      "Latency of the admission requests by operation.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"operation"})
+
+var patchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "patch_size_bytes",
+	Help:      "Size of the JSON patches of the allowed admission requests.",
+	Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+})
+
+// observeAdmission Counts the admission request and observes its latency
+func observeAdmission(operation string, outcome string, start time.Time) {
+	admissionRequests.WithLabelValues(operation, outcome).Inc()
+	admissionDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,137 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type fakePodPatcher struct {
+	patches []PatchOperation
+	err     error
+}
+
+func (patcher fakePodPatcher) PatchPodCreate(_ context.Context, _ string, _ corev1.Pod) ([]PatchOperation, error) {
+	return patcher.patches, patcher.err
+}
+
+func (patcher fakePodPatcher) PatchPodUpdate(_ context.Context, _ string, _ corev1.Pod, _ corev1.Pod) ([]PatchOperation, error) {
+	return patcher.patches, patcher.err
+}
+
+func (patcher fakePodPatcher) PatchPodDelete(_ context.Context, _ string, _ corev1.Pod) ([]PatchOperation, error) {
+	return patcher.patches, patcher.err
+}
+
+// admissionReviewBody AdmissionReview of the creation of a pod named app in the test namespace
+func admissionReviewBody(t *testing.T) string {
+	pod, err := json.Marshal(corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}})
+	assert.NoError(t, err)
+	review, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "1234",
+			Namespace: "test",
+			Name:      "app",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: pod},
+		},
+	})
+	assert.NoError(t, err)
+	return string(review)
+}
+
+// histogramSamples Number and sum of the samples observed by the histogram
+func histogramSamples(t *testing.T, observer prometheus.Observer) (uint64, float64) {
+	metric := &dto.Metric{}
+	assert.NoError(t, observer.(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func TestHandler_HandleAdmissionMetrics(t *testing.T) {
+	patches := []PatchOperation{{Op: "add", Path: "/metadata/labels", Value: map[string]string{"app": "test"}}}
+	patchBytes, _ := json.Marshal(patches)
+	tests := []struct {
+		name          string
+		patcher       fakePodPatcher
+		contentType   string
+		body          func(t *testing.T) string
+		wantOperation string
+		wantOutcome   string
+		wantPatchSize float64
+	}{
+		{
+			name:          "allowed with patches",
+			patcher:       fakePodPatcher{patches: patches},
+			contentType:   "application/json",
+			body:          admissionReviewBody,
+			wantOperation: "CREATE",
+			wantOutcome:   admissionOutcomeAllowed,
+			wantPatchSize: float64(len(patchBytes)),
+		},
+		{
+			name:          "allowed without patches",
+			contentType:   "application/json",
+			body:          admissionReviewBody,
+			wantOperation: "CREATE",
+			wantOutcome:   admissionOutcomeAllowed,
+		},
+		{
+			name:          "denied",
+			patcher:       fakePodPatcher{err: errors.New("denied")},
+			contentType:   "application/json",
+			body:          admissionReviewBody,
+			wantOperation: "CREATE",
+			wantOutcome:   admissionOutcomeDenied,
+		},
+		{
+			name:        "wrong content type",
+			contentType: "text/plain",
+			body:        admissionReviewBody,
+			wantOutcome: admissionOutcomeError,
+		},
+		{
+			name:        "undecodable body",
+			contentType: "application/json",
+			body:        func(t *testing.T) string { return "{" },
+			wantOutcome: admissionOutcomeError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &Handler{Handler: &PodAdmissionRequestHandler{PodHandler: tt.patcher}}
+			requests := admissionRequests.WithLabelValues(tt.wantOperation, tt.wantOutcome)
+			requestsBefore := testutil.ToFloat64(requests)
+			durationsBefore, _ := histogramSamples(t, admissionDuration.WithLabelValues(tt.wantOperation))
+			patchesBefore, patchBytesBefore := histogramSamples(t, patchSize)
+
+			request := httptest.NewRequest(http.MethodPost, "/mutate", strings.NewReader(tt.body(t)))
+			request.Header.Set("Content-Type", tt.contentType)
+			handler.HandleAdmission(httptest.NewRecorder(), request)
+
+			assert.Equal(t, requestsBefore+1, testutil.ToFloat64(requests), "admission_requests_total")
+			durations, _ := histogramSamples(t, admissionDuration.WithLabelValues(tt.wantOperation))
+			assert.Equal(t, durationsBefore+1, durations, "admission_duration_seconds")
+			patchesAfter, patchBytesAfter := histogramSamples(t, patchSize)
+			if tt.wantPatchSize > 0 {
+				assert.Equal(t, patchesBefore+1, patchesAfter, "patch_size_bytes")
+			} else {
+				assert.Equal(t, patchesBefore, patchesAfter, "patch_size_bytes")
+			}
+			assert.Equal(t, patchBytesBefore+tt.wantPatchSize, patchBytesAfter, "patch_size_bytes")
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package admission
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission")
+
+// admissionUIDKey Span attribute of the UID of the admission request
+const admissionUIDKey = attribute.Key("k8s.admission.uid")
+
+// admissionOperationKey Span attribute of the operation of the admission request
+const admissionOperationKey = attribute.Key("k8s.admission.operation")
+
+// failSpan Records the error failing the span
+func failSpan(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
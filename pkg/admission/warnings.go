@@ -0,0 +1,25 @@
+package admission
+
+import "context"
+
+type warningsKey struct{}
+
+// WithWarnings Returns a context collecting the warnings returned to the client with the AdmissionResponse
+func WithWarnings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, warningsKey{}, new([]string))
+}
+
+// AddWarning Adds a warning to the AdmissionResponse of the request, ignored when the context does not collect warnings
+func AddWarning(ctx context.Context, warning string) {
+	if warnings, ok := ctx.Value(warningsKey{}).(*[]string); ok {
+		*warnings = append(*warnings, warning)
+	}
+}
+
+// Warnings Returns the warnings collected for the request
+func Warnings(ctx context.Context) []string {
+	if warnings, ok := ctx.Value(warningsKey{}).(*[]string); ok {
+		return *warnings
+	}
+	return nil
+}
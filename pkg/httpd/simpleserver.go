@@ -1,30 +1,45 @@
 package httpd
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
 	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/webhook"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 )
 
 /*SimpleServer is the required config to create httpd server*/
 type SimpleServer struct {
-	Local       bool
-	Port        int
-	MetricsPort int
-	CertFile    string
-	KeyFile     string
-	Patcher     webhook.SidecarInjectorPatcher
-	Debug       bool
+	Local               bool
+	Port                int
+	MetricsPort         int
+	CertFile            string
+	KeyFile             string
+	Patcher             webhook.SidecarInjectorPatcher
+	Debug               bool
+	Events              bool
+	EventBurst          int
+	EventInterval       time.Duration
+	Tracing             Tracing
+	PodCacheSyncTimeout time.Duration
 }
 
 /*Start the simple http server supporting TLS*/
@@ -34,7 +49,26 @@ func (simpleServer *SimpleServer) Start() error {
 		return err
 	}
 
+	shutdownTracing, err := simpleServer.Tracing.start(context.Background())
+	if err != nil {
+		return errors.Wrapf(err, "error setting up tracing")
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Errorf("error flushing spans - %v", err)
+		}
+	}()
+
 	simpleServer.Patcher.K8sClient = k8sClient
+	if simpleServer.Patcher.NamespaceBudgetsEnabled() {
+		simpleServer.Patcher.PodLister, err = simpleServer.startPodLister(k8sClient)
+		if err != nil {
+			return err
+		}
+	}
+	if simpleServer.Events {
+		simpleServer.Patcher.EventRecorder = simpleServer.createEventRecorder(k8sClient)
+	}
 	server := &http.Server{
 		Addr: fmt.Sprintf(":%d", simpleServer.Port),
 	}
@@ -78,6 +112,34 @@ func (simpleServer *SimpleServer) startMetricsServer(metricsHandler http.Handler
 	}
 }
 
+// startPodLister Lists the pods from an informer cache, so that namespace budgets do not list pods on every admission.
+// Only the fields needed to sum the sidecar usage of the pods are cached.
+func (simpleServer *SimpleServer) startPodLister(k8sClient kubernetes.Interface) (corelisters.PodLister, error) {
+	listWatch := cache.NewListWatchFromClient(k8sClient.CoreV1().RESTClient(), "pods", metav1.NamespaceAll, fields.Everything())
+	indexer, informer := cache.NewTransformingIndexerInformer(listWatch, &corev1.Pod{}, 0, cache.ResourceEventHandlerFuncs{},
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, simpleServer.Patcher.TrimCachedPod)
+	stop := make(chan struct{})
+	go informer.Run(stop)
+	ctx, cancel := context.WithTimeout(context.Background(), simpleServer.PodCacheSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		close(stop)
+		return nil, fmt.Errorf("error syncing the pod cache of the namespace budgets within %v, check that pods can be listed and watched", simpleServer.PodCacheSyncTimeout)
+	}
+	return corelisters.NewPodLister(indexer), nil
+}
+
+// createEventRecorder Create a recorder rate limiting the events of each object
+func (simpleServer *SimpleServer) createEventRecorder(k8sClient kubernetes.Interface) record.EventRecorder {
+	correlatorOptions := record.CorrelatorOptions{BurstSize: simpleServer.EventBurst}
+	if simpleServer.EventInterval > 0 {
+		correlatorOptions.QPS = float32(1 / simpleServer.EventInterval.Seconds())
+	}
+	broadcaster := record.NewBroadcasterWithCorrelatorOptions(correlatorOptions)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "kubernetes-sidecar-injector"})
+}
+
 // CreateClient Create the server
 func (simpleServer *SimpleServer) CreateClient() (*kubernetes.Clientset, error) {
 	config, err := simpleServer.buildConfig()
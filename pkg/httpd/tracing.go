@@ -0,0 +1,70 @@
+package httpd
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const (
+	// TracingProtocolGRPC Spans are exported with OTLP over gRPC
+	TracingProtocolGRPC = "grpc"
+	// TracingProtocolHTTP Spans are exported with OTLP over HTTP
+	TracingProtocolHTTP = "http"
+)
+
+// Tracing OTLP exporter of the spans, disabled without an endpoint
+type Tracing struct {
+	Endpoint    string
+	Protocol    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+// tracingExporter Creates the OTLP exporter for the configured protocol
+func (tracing *Tracing) tracingExporter(ctx context.Context) (*otlptrace.Exporter, error) {
+	switch tracing.Protocol {
+	case TracingProtocolGRPC, "":
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(tracing.Endpoint)}
+		if tracing.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	case TracingProtocolHTTP:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(tracing.Endpoint)}
+		if tracing.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing protocol %s, expected grpc or http", tracing.Protocol)
+	}
+}
+
+// start Registers the tracer provider exporting the spans, returning the function flushing them on shutdown
+func (tracing *Tracing) start(ctx context.Context) (func(context.Context) error, error) {
+	if tracing.Endpoint == "" {
+		// the global tracer provider is a no-op until one is registered
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := tracing.tracingExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracing.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("kubernetes-sidecar-injector"))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SidecarBootstrap Artifacts copied by the sidecar init containers into a shared volume and loaded by the application containers
+type SidecarBootstrap struct {
+	Volume     string          `yaml:"volume"`
+	MountPath  string          `yaml:"mountPath"`
+	Containers []string        `yaml:"containers"`
+	Launcher   []string        `yaml:"launcher"`
+	Env        []corev1.EnvVar `yaml:"env"`
+}
+
+// bootstrapVolume Name of the shared volume of a bootstrap sidecar
+func bootstrapVolume(sidecar Sidecar) string {
+	if sidecar.Bootstrap.Volume != "" {
+		return sidecar.Bootstrap.Volume
+	}
+	return sidecar.Name + "-bootstrap"
+}
+
+// applyBootstrap Adds the shared volume to a bootstrap sidecar and mounts it into its init containers
+func applyBootstrap(pod *corev1.Pod, sidecar Sidecar) (Sidecar, error) {
+	if sidecar.Bootstrap == nil {
+		return sidecar, nil
+	}
+	if sidecar.Bootstrap.MountPath == "" {
+		return sidecar, fmt.Errorf("bootstrap requires a mountPath")
+	}
+	volume := bootstrapVolume(sidecar)
+	if volumeIndex(pod.Spec.Volumes, volume) >= 0 || volumeIndex(sidecar.Volumes, volume) >= 0 {
+		return sidecar, fmt.Errorf("bootstrap volume %s is already defined", volume)
+	}
+	sidecar.Volumes = append(append([]corev1.Volume(nil), sidecar.Volumes...), corev1.Volume{
+		Name:         volume,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	initContainers := make([]corev1.Container, 0, len(sidecar.InitContainers))
+	for _, container := range sidecar.InitContainers {
+		if !mountsVolume(container, volume) {
+			container.VolumeMounts = append(append([]corev1.VolumeMount(nil), container.VolumeMounts...), corev1.VolumeMount{Name: volume, MountPath: sidecar.Bootstrap.MountPath})
+		}
+		initContainers = append(initContainers, container)
+	}
+	if sidecar.InitContainers != nil {
+		sidecar.InitContainers = initContainers
+	}
+	return sidecar, nil
+}
+
+func mountsVolume(container corev1.Container, volume string) bool {
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == volume {
+			return true
+		}
+	}
+	return false
+}
+
+// bootstrapPatches Mounts the shared volume into the selected application containers, and prepends the launcher to
+// their command or sets the bootstrap environment variables
+func bootstrapPatches(ctx context.Context, source string, pod *corev1.Pod, applications []corev1.Container, sidecar Sidecar) []admission.PatchOperation {
+	if sidecar.Bootstrap == nil {
+		return nil
+	}
+	bootstrap := sidecar.Bootstrap
+	mount := corev1.VolumeMount{Name: bootstrapVolume(sidecar), MountPath: bootstrap.MountPath, ReadOnly: true}
+	var patches []admission.PatchOperation
+	for _, application := range applications {
+		if len(bootstrap.Containers) > 0 && !slices.Contains(bootstrap.Containers, application.Name) {
+			continue
+		}
+		index := containerIndex(pod.Spec.Containers, application.Name)
+		if index < 0 {
+			continue
+		}
+		container := &pod.Spec.Containers[index]
+		path := fmt.Sprintf("/spec/containers/%d", index)
+		patches = append(patches, createArrayPatches([]corev1.VolumeMount{mount}, container.VolumeMounts, path+"/volumeMounts")...)
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+
+		if len(bootstrap.Launcher) > 0 {
+			if len(container.Command) == 0 {
+				warnLifecycle(ctx, fmt.Sprintf("%s cannot prepend its launcher to container %s without a command", source, container.Name))
+			} else {
+				command := append(append([]string(nil), bootstrap.Launcher...), container.Command...)
+				patches = append(patches, admission.PatchOperation{Op: "replace", Path: path + "/command", Value: command})
+				container.Command = command
+			}
+		}
+
+		var added []corev1.EnvVar
+		for _, env := range bootstrap.Env {
+			existing := envIndex(container.Env, env.Name)
+			switch {
+			case existing < 0:
+				added = append(added, env)
+			case container.Env[existing].ValueFrom != nil || env.ValueFrom != nil:
+				warnLifecycle(ctx, fmt.Sprintf("%s cannot extend variable %s of container %s referencing a value", source, env.Name, container.Name))
+			default:
+				value := container.Env[existing].Value + " " + env.Value
+				patches = append(patches, admission.PatchOperation{Op: "replace", Path: fmt.Sprintf("%s/env/%d/value", path, existing), Value: value})
+				container.Env[existing].Value = value
+			}
+		}
+		patches = append(patches, createArrayPatches(added, container.Env, path+"/env")...)
+		container.Env = append(container.Env, added...)
+	}
+	return patches
+}
+
+func envIndex(env []corev1.EnvVar, name string) int {
+	for index, variable := range env {
+		if variable.Name == name {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSidecarInjectorPatcher_PatchPodCreateBootstrap(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "otel", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: otel
+                       bootstrap:
+                         mountPath: /otel
+                         containers: [ api, worker ]
+                         launcher: [ /otel/launch.sh ]
+                         env:
+                           - name: JAVA_TOOL_OPTIONS
+                             value: -javaagent:/otel/javaagent.jar
+                       initContainers:
+                         - name: otel-copy
+                           command: [ cp, /javaagent.jar, /otel/ ]`,
+		},
+	}
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "otel"},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{
+			{Name: "api", Command: []string{"java", "-jar", "api.jar"}},
+			{Name: "worker", Env: []v1.EnvVar{{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g"}}},
+			{Name: "nginx"},
+		}},
+	}
+	patcher := &SidecarInjectorPatcher{
+		K8sClient:      fake.NewSimpleClientset(configmap),
+		InjectPrefix:   "sidecar-injector.expedia.com",
+		InjectName:     "inject",
+		SidecarDataKey: "sidecars.yaml",
+	}
+	ctx := admission.WithWarnings(context.Background())
+	got, err := patcher.PatchPodCreate(ctx, "test", pod)
+	assert.NoError(t, err)
+	mount := v1.VolumeMount{Name: "otel-bootstrap", MountPath: "/otel", ReadOnly: true}
+	assert.Equal(t, []admission.PatchOperation{
+		{Op: "add", Path: "/spec/initContainers", Value: []v1.Container{{
+			Name:         "otel-copy",
+			Command:      []string{"cp", "/javaagent.jar", "/otel/"},
+			VolumeMounts: []v1.VolumeMount{{Name: "otel-bootstrap", MountPath: "/otel"}},
+		}}},
+		{Op: "add", Path: "/spec/volumes", Value: []v1.Volume{{Name: "otel-bootstrap", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}},
+		{Op: "add", Path: "/spec/containers/0/volumeMounts", Value: []v1.VolumeMount{mount}},
+		{Op: "replace", Path: "/spec/containers/0/command", Value: []string{"/otel/launch.sh", "java", "-jar", "api.jar"}},
+		{Op: "add", Path: "/spec/containers/0/env", Value: []v1.EnvVar{{Name: "JAVA_TOOL_OPTIONS", Value: "-javaagent:/otel/javaagent.jar"}}},
+		{Op: "add", Path: "/spec/containers/1/volumeMounts", Value: []v1.VolumeMount{mount}},
+		{Op: "replace", Path: "/spec/containers/1/env/0/value", Value: "-Xmx1g -javaagent:/otel/javaagent.jar"},
+	}, got)
+	assert.Equal(t, []string{"sidecar otel from configmap test/otel cannot prepend its launcher to container worker without a command"}, admission.Warnings(ctx))
+}
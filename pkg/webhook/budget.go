@@ -0,0 +1,315 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// BudgetActionDeny Pods exceeding a sidecar budget are denied
+	BudgetActionDeny = "deny"
+	// BudgetActionWarn Pods exceeding a sidecar budget are admitted with a warning
+	BudgetActionWarn = "warn"
+)
+
+// ResourceBudget Limits on the sidecars injected into a pod or a namespace
+type ResourceBudget struct {
+	MaxSidecars int                 `yaml:"maxSidecars"`
+	Requests    corev1.ResourceList `yaml:"requests"`
+	Limits      corev1.ResourceList `yaml:"limits"`
+}
+
+// String Formats the budget for the command line
+func (budget *ResourceBudget) String() string {
+	if budget == nil || budget.empty() {
+		return ""
+	}
+	value, _ := yaml.Marshal(budget)
+	return string(value)
+}
+
+// Set Parses the budget from the command line as YAML or JSON
+func (budget *ResourceBudget) Set(value string) error {
+	return yaml.Unmarshal([]byte(value), budget)
+}
+
+// Type Describes the command line value
+func (budget *ResourceBudget) Type() string {
+	return "budget"
+}
+
+func (budget ResourceBudget) empty() bool {
+	return budget.MaxSidecars == 0 && len(budget.Requests) == 0 && len(budget.Limits) == 0
+}
+
+// violations Lists how the usage exceeds the budget
+func (budget ResourceBudget) violations(usage sidecarUsage) []string {
+	var violations []string
+	if budget.MaxSidecars > 0 && usage.Sidecars > budget.MaxSidecars {
+		violations = append(violations, fmt.Sprintf("%d sidecars exceed the maximum of %d", usage.Sidecars, budget.MaxSidecars))
+	}
+	violations = append(violations, resourceViolations("requests", budget.Requests, usage.Requests)...)
+	violations = append(violations, resourceViolations("limits", budget.Limits, usage.Limits)...)
+	return violations
+}
+
+func resourceViolations(kind string, budget corev1.ResourceList, usage corev1.ResourceList) []string {
+	var violations []string
+	for _, name := range sortedResourceNames(budget) {
+		max := budget[name]
+		if used, ok := usage[name]; ok && used.Cmp(max) > 0 {
+			violations = append(violations, fmt.Sprintf("%s.%s %s exceed %s", kind, name, used.String(), max.String()))
+		}
+	}
+	return violations
+}
+
+func sortedResourceNames(resources corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// sidecarUsage Number and summed resources of injected sidecars
+type sidecarUsage struct {
+	Sidecars int
+	Requests corev1.ResourceList
+	Limits   corev1.ResourceList
+}
+
+func (usage *sidecarUsage) add(other sidecarUsage) {
+	usage.Sidecars += other.Sidecars
+	usage.Requests = addResources(usage.Requests, other.Requests)
+	usage.Limits = addResources(usage.Limits, other.Limits)
+}
+
+func addResources(total corev1.ResourceList, resources corev1.ResourceList) corev1.ResourceList {
+	if len(resources) == 0 {
+		return total
+	}
+	if total == nil {
+		total = corev1.ResourceList{}
+	}
+	for name, quantity := range resources {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+	return total
+}
+
+// injectedUsage Sums the resources of all containers of the injected sidecars
+func injectedUsage(sidecars []Sidecar) sidecarUsage {
+	usage := sidecarUsage{Sidecars: len(sidecars)}
+	for _, sidecar := range sidecars {
+		for _, container := range append(append([]corev1.Container{}, sidecar.InitContainers...), sidecar.Containers...) {
+			usage.Requests = addResources(usage.Requests, container.Resources.Requests)
+			usage.Limits = addResources(usage.Limits, container.Resources.Limits)
+		}
+	}
+	return usage
+}
+
+// podBudgetAnnotation Namespace annotation overriding the sidecar budget of each pod
+func (patcher *SidecarInjectorPatcher) podBudgetAnnotation() string {
+	return patcher.InjectPrefix + "/pod-sidecar-budget"
+}
+
+// namespaceBudgetAnnotation Namespace annotation overriding the sidecar budget of all pods in the namespace
+func (patcher *SidecarInjectorPatcher) namespaceBudgetAnnotation() string {
+	return patcher.InjectPrefix + "/namespace-sidecar-budget"
+}
+
+// injectedUsageAnnotation Pod annotation recording the injected containers counted against the namespace budget
+func (patcher *SidecarInjectorPatcher) injectedUsageAnnotation() string {
+	return patcher.InjectPrefix + "/injected-usage"
+}
+
+// namespaceBudget Resolves a budget from the namespace annotation, falling back to the server budget
+func namespaceBudget(ctx context.Context, ns *corev1.Namespace, annotation string, fallback ResourceBudget) ResourceBudget {
+	if ns == nil {
+		return fallback
+	}
+	value, ok := ns.GetAnnotations()[annotation]
+	if !ok {
+		return fallback
+	}
+	var budget ResourceBudget
+	if err := budget.Set(value); err != nil {
+		admission.Logger(ctx).Errorf("error unmarshalling %s from namespace %s, using the default budget - %v", annotation, ns.GetName(), err)
+		return fallback
+	}
+	return budget
+}
+
+// injectedContainers Number of injected sidecars and names of their containers, recorded on the pod so that its usage
+// is summed from the resources of the containers themselves
+type injectedContainers struct {
+	Sidecars       int      `json:"sidecars"`
+	InitContainers []string `json:"initContainers,omitempty"`
+	Containers     []string `json:"containers,omitempty"`
+}
+
+// injectedContainerNames Records the containers of the injected sidecars
+func injectedContainerNames(sidecars []Sidecar) injectedContainers {
+	injected := injectedContainers{Sidecars: len(sidecars)}
+	for _, sidecar := range sidecars {
+		for _, container := range sidecar.InitContainers {
+			injected.InitContainers = append(injected.InitContainers, container.Name)
+		}
+		for _, container := range sidecar.Containers {
+			injected.Containers = append(injected.Containers, container.Name)
+		}
+	}
+	return injected
+}
+
+// podUsage Sums the resources of the injected containers of the pod
+func podUsage(pod *corev1.Pod, injected injectedContainers) (sidecarUsage, error) {
+	if injected.Sidecars < 0 || injected.Sidecars > len(injected.InitContainers)+len(injected.Containers) {
+		return sidecarUsage{}, fmt.Errorf("%d sidecars do not match %d injected containers", injected.Sidecars, len(injected.InitContainers)+len(injected.Containers))
+	}
+	usage := sidecarUsage{Sidecars: injected.Sidecars}
+	addContainers := func(containers []corev1.Container, names []string) {
+		for _, container := range containers {
+			if slices.Contains(names, container.Name) {
+				usage.Requests = addResources(usage.Requests, container.Resources.Requests)
+				usage.Limits = addResources(usage.Limits, container.Resources.Limits)
+			}
+		}
+	}
+	addContainers(pod.Spec.InitContainers, injected.InitContainers)
+	addContainers(pod.Spec.Containers, injected.Containers)
+	return usage, nil
+}
+
+// podBudgetsEnabled Whether pod budgets apply, from the server budget or from namespace annotations
+func (patcher *SidecarInjectorPatcher) podBudgetsEnabled() bool {
+	return patcher.PodBudgets || !patcher.PodBudget.empty()
+}
+
+// NamespaceBudgetsEnabled Whether namespace budgets apply, so that the pods they are summed from need to be cached
+func (patcher *SidecarInjectorPatcher) NamespaceBudgetsEnabled() bool {
+	return patcher.NamespaceBudgets || !patcher.NamespaceBudget.empty()
+}
+
+// TrimCachedPod Keeps only the fields of a pod needed to sum its sidecar usage, so that the pod cache does not hold
+// whole pod specs
+func (patcher *SidecarInjectorPatcher) TrimCachedPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	trimmed := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		Status: corev1.PodStatus{Phase: pod.Status.Phase},
+	}
+	value, ok := pod.Annotations[patcher.injectedUsageAnnotation()]
+	if !ok {
+		return trimmed, nil
+	}
+	trimmed.Annotations = map[string]string{patcher.injectedUsageAnnotation(): value}
+	trimContainers := func(containers []corev1.Container) []corev1.Container {
+		var trimmed []corev1.Container
+		for _, container := range containers {
+			trimmed = append(trimmed, corev1.Container{Name: container.Name, Resources: container.Resources})
+		}
+		return trimmed
+	}
+	trimmed.Spec.InitContainers = trimContainers(pod.Spec.InitContainers)
+	trimmed.Spec.Containers = trimContainers(pod.Spec.Containers)
+	return trimmed, nil
+}
+
+// namespaceUsage Sums the resources of the injected containers of the active pods of the namespace, as cached by the
+// pod lister
+func (patcher *SidecarInjectorPatcher) namespaceUsage(ctx context.Context, namespace string) (sidecarUsage, error) {
+	var usage sidecarUsage
+	if patcher.PodLister == nil {
+		return usage, fmt.Errorf("no pod lister is configured")
+	}
+	pods, err := patcher.PodLister.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		return usage, err
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		value, ok := pod.GetAnnotations()[patcher.injectedUsageAnnotation()]
+		if !ok {
+			continue
+		}
+		var injected injectedContainers
+		err := json.Unmarshal([]byte(value), &injected)
+		var containersUsage sidecarUsage
+		if err == nil {
+			containersUsage, err = podUsage(pod, injected)
+		}
+		if err != nil {
+			admission.Logger(ctx).Warnf("ignoring invalid %s on pod %s/%s - %v", patcher.injectedUsageAnnotation(), namespace, pod.GetName(), err)
+			continue
+		}
+		usage.add(containersUsage)
+	}
+	return usage, nil
+}
+
+// checkBudgets Verifies the injected sidecars against the pod and namespace budgets.
+// Returns the annotations recording the usage of the pod when a namespace budget applies.
+func (patcher *SidecarInjectorPatcher) checkBudgets(ctx context.Context, namespace string, ns *corev1.Namespace, sidecars []Sidecar) (map[string]string, error) {
+	var podBudget, nsBudget ResourceBudget
+	if patcher.podBudgetsEnabled() {
+		podBudget = namespaceBudget(ctx, ns, patcher.podBudgetAnnotation(), patcher.PodBudget)
+	}
+	if patcher.NamespaceBudgetsEnabled() {
+		nsBudget = namespaceBudget(ctx, ns, patcher.namespaceBudgetAnnotation(), patcher.NamespaceBudget)
+	}
+	if podBudget.empty() && nsBudget.empty() {
+		return nil, nil
+	}
+	usage := injectedUsage(sidecars)
+	violations := podBudget.violations(usage)
+	status := map[string]string{}
+	if !nsBudget.empty() {
+		total, err := patcher.namespaceUsage(ctx, namespace)
+		if err != nil {
+			admission.Logger(ctx).Errorf("error listing pods of namespace %s, skipping namespace sidecar budget - %v", namespace, err)
+		} else {
+			total.add(usage)
+			for _, violation := range nsBudget.violations(total) {
+				violations = append(violations, "namespace "+violation)
+			}
+		}
+		value, _ := json.Marshal(injectedContainerNames(sidecars))
+		status[patcher.injectedUsageAnnotation()] = string(value)
+	}
+	if len(violations) == 0 {
+		return status, nil
+	}
+	message := fmt.Sprintf("sidecars exceed their budget: %s", strings.Join(violations, ", "))
+	if patcher.BudgetAction == BudgetActionWarn {
+		admission.Logger(ctx).Warnf("pod in namespace %s: %s", namespace, message)
+		admission.AddWarning(ctx, message)
+		return status, nil
+	}
+	return nil, fmt.Errorf("%s", message)
+}
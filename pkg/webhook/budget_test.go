@@ -0,0 +1,287 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestResourceBudget_Set(t *testing.T) {
+	var budget ResourceBudget
+	err := budget.Set(`{maxSidecars: 2, requests: {cpu: 500m}, limits: {memory: 1Gi}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, ResourceBudget{
+		MaxSidecars: 2,
+		Requests:    v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+		Limits:      v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+	}, budget)
+}
+
+func TestSidecarInjectorPatcher_checkBudgets(t *testing.T) {
+	sidecar := func(cpu string) Sidecar {
+		return Sidecar{Containers: []v1.Container{{
+			Name:      "sidecar",
+			Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}},
+		}}}
+	}
+	cpu := func(cpu string) v1.ResourceRequirements {
+		return v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}}
+	}
+	runningPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "running",
+			Namespace:   "test",
+			Annotations: map[string]string{"sidecar-injector.expedia.com/injected-usage": `{"sidecars":1,"containers":["envoy"]}`},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app", Resources: cpu("4")}, {Name: "envoy", Resources: cpu("800m")}}},
+	}
+	completedPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "completed",
+			Namespace:   "test",
+			Annotations: map[string]string{"sidecar-injector.expedia.com/injected-usage": `{"sidecars":1,"containers":["envoy"]}`},
+		},
+		Spec:   v1.PodSpec{Containers: []v1.Container{{Name: "envoy", Resources: cpu("2")}}},
+		Status: v1.PodStatus{Phase: v1.PodSucceeded},
+	}
+	forgedPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "forged",
+			Namespace:   "test",
+			Annotations: map[string]string{"sidecar-injector.expedia.com/injected-usage": `{"sidecars":-50,"requests":{"cpu":"-100"}}`},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+	}
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pod := range []*v1.Pod{runningPod, completedPod, forgedPod} {
+		assert.NoError(t, pods.Add(pod))
+	}
+	tests := []struct {
+		name         string
+		patcher      SidecarInjectorPatcher
+		ns           *v1.Namespace
+		sidecars     []Sidecar
+		want         map[string]string
+		wantWarnings []string
+		wantErr      assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "no budget",
+			sidecars: []Sidecar{sidecar("1"), sidecar("1")},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "within pod budget",
+			patcher:  SidecarInjectorPatcher{PodBudget: ResourceBudget{MaxSidecars: 2, Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			want:     map[string]string{},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "too many sidecars",
+			patcher:  SidecarInjectorPatcher{PodBudget: ResourceBudget{MaxSidecars: 1}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			wantErr:  assert.Error,
+		},
+		{
+			name:     "pod requests exceeded warned about",
+			patcher:  SidecarInjectorPatcher{BudgetAction: BudgetActionWarn, PodBudget: ResourceBudget{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("400m")}}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			want:     map[string]string{},
+			wantWarnings: []string{
+				"sidecars exceed their budget: requests.cpu 500m exceed 400m",
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "namespace annotation overrides server budget",
+			patcher: SidecarInjectorPatcher{PodBudget: ResourceBudget{MaxSidecars: 1}},
+			ns: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{"sidecar-injector.expedia.com/pod-sidecar-budget": "maxSidecars: 3"},
+			}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			want:     map[string]string{},
+			wantErr:  assert.NoError,
+		},
+		{
+			name: "namespace annotation ignored without pod budgets",
+			ns: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{"sidecar-injector.expedia.com/pod-sidecar-budget": "maxSidecars: 1"},
+			}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:    "namespace annotation with pod budgets",
+			patcher: SidecarInjectorPatcher{PodBudgets: true},
+			ns: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{"sidecar-injector.expedia.com/pod-sidecar-budget": "maxSidecars: 1"},
+			}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			wantErr:  assert.Error,
+		},
+		{
+			name:     "within namespace budget",
+			patcher:  SidecarInjectorPatcher{NamespaceBudget: ResourceBudget{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}},
+			sidecars: []Sidecar{sidecar("200m")},
+			want:     map[string]string{"sidecar-injector.expedia.com/injected-usage": `{"sidecars":1,"containers":["sidecar"]}`},
+			wantErr:  assert.NoError,
+		},
+		{
+			name: "namespace annotation ignored without namespace budgets",
+			ns: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{"sidecar-injector.expedia.com/namespace-sidecar-budget": "maxSidecars: 1"},
+			}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:    "namespace annotation with namespace budgets",
+			patcher: SidecarInjectorPatcher{NamespaceBudgets: true},
+			ns: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{"sidecar-injector.expedia.com/namespace-sidecar-budget": "maxSidecars: 2"},
+			}},
+			sidecars: []Sidecar{sidecar("200m"), sidecar("300m")},
+			wantErr:  assert.Error,
+		},
+		{
+			name:     "namespace budget exceeded",
+			patcher:  SidecarInjectorPatcher{NamespaceBudget: ResourceBudget{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}},
+			sidecars: []Sidecar{sidecar("300m")},
+			wantErr:  assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := tt.patcher
+			patcher.PodLister = corelisters.NewPodLister(pods)
+			patcher.InjectPrefix = "sidecar-injector.expedia.com"
+			ctx := admission.WithWarnings(context.Background())
+			got, err := patcher.checkBudgets(ctx, "test", tt.ns, tt.sidecars)
+			if !tt.wantErr(t, err, "checkBudgets(%v)", tt.sidecars) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "checkBudgets(%v)", tt.sidecars)
+			assert.Equalf(t, tt.wantWarnings, admission.Warnings(ctx), "checkBudgets(%v)", tt.sidecars)
+		})
+	}
+}
+
+func Test_podUsage(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "otel-init", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}}}},
+		Containers: []v1.Container{
+			{Name: "app", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4")}}},
+			{Name: "otel", Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")}}},
+		},
+	}}
+	tests := []struct {
+		name     string
+		injected injectedContainers
+		want     sidecarUsage
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "injected containers",
+			injected: injectedContainers{Sidecars: 1, InitContainers: []string{"otel-init"}, Containers: []string{"otel"}},
+			want: sidecarUsage{
+				Sidecars: 1,
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:     "missing container",
+			injected: injectedContainers{Sidecars: 1, Containers: []string{"envoy"}},
+			want:     sidecarUsage{Sidecars: 1},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "negative sidecars",
+			injected: injectedContainers{Sidecars: -50},
+			wantErr:  assert.Error,
+		},
+		{
+			name:     "more sidecars than containers",
+			injected: injectedContainers{Sidecars: 3, Containers: []string{"otel"}},
+			wantErr:  assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := podUsage(pod, tt.injected)
+			if !tt.wantErr(t, err, "podUsage(%v)", tt.injected) || err != nil {
+				return
+			}
+			assert.Truef(t, equality.Semantic.DeepEqual(tt.want, got), "podUsage(%v) = %v, want %v", tt.injected, got, tt.want)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_TrimCachedPod(t *testing.T) {
+	resources := v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "test",
+			Labels:      map[string]string{"app": "test"},
+			Annotations: map[string]string{"sidecar-injector.expedia.com/injected-usage": `{"sidecars":1,"containers":["envoy"]}`, "other": "value"},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "app", Image: "app", Env: []v1.EnvVar{{Name: "A", Value: "a"}}}, {Name: "envoy", Image: "envoy", Resources: resources}},
+			Volumes:    []v1.Volume{{Name: "data"}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.0.0.1"},
+	}
+	tests := []struct {
+		name string
+		obj  interface{}
+		want interface{}
+	}{
+		{
+			name: "injected pod",
+			obj:  pod,
+			want: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "app",
+					Namespace:   "test",
+					Annotations: map[string]string{"sidecar-injector.expedia.com/injected-usage": `{"sidecars":1,"containers":["envoy"]}`},
+				},
+				Spec:   v1.PodSpec{Containers: []v1.Container{{Name: "app"}, {Name: "envoy", Resources: resources}}},
+				Status: v1.PodStatus{Phase: v1.PodRunning},
+			},
+		},
+		{
+			name: "pod without sidecars",
+			obj:  &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}, Spec: pod.Spec},
+			want: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}},
+		},
+		{
+			name: "deleted pod",
+			obj:  cache.DeletedFinalStateUnknown{Key: "test/app", Obj: pod},
+			want: cache.DeletedFinalStateUnknown{Key: "test/app", Obj: pod},
+		},
+	}
+	patcher := &SidecarInjectorPatcher{InjectPrefix: "sidecar-injector.expedia.com"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patcher.TrimCachedPod(tt.obj)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
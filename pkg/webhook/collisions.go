@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	// NameCollisionPolicyFail Pods are denied when sidecar names collide with differing definitions in the pod
+	NameCollisionPolicyFail = "fail"
+	// NameCollisionPolicyRename Colliding sidecar names are prefixed with the sidecar name
+	NameCollisionPolicyRename = "rename"
+)
+
+// renamedName Sidecar specific name of a colliding container or volume
+func renamedName(sidecar Sidecar, name string) string {
+	return sidecar.Name + "-" + name
+}
+
+// resolveNameCollisions Removes sidecar containers, volumes and pull secrets already defined identically in the pod,
+// and fails or renames the ones defined differently
+func (patcher *SidecarInjectorPatcher) resolveNameCollisions(pod *corev1.Pod, sidecar Sidecar) (Sidecar, error) {
+	var err error
+	volumeNames := map[string]bool{}
+	for _, volume := range pod.Spec.Volumes {
+		volumeNames[volume.Name] = true
+	}
+	renamedVolumes := map[string]string{}
+	var volumes []corev1.Volume
+	for _, volume := range sidecar.Volumes {
+		if !volumeNames[volume.Name] {
+			volumes = append(volumes, volume)
+			continue
+		}
+		if index := volumeIndex(pod.Spec.Volumes, volume.Name); equality.Semantic.DeepEqual(pod.Spec.Volumes[index], volume) {
+			continue
+		}
+		renamed, err := patcher.renameColliding("volume", sidecar, volume.Name, volumeNames)
+		if err != nil {
+			return sidecar, err
+		}
+		renamedVolumes[volume.Name] = renamed
+		volume.Name = renamed
+		volumes = append(volumes, volume)
+	}
+	if sidecar.Volumes != nil {
+		sidecar.Volumes = volumes
+	}
+
+	containerNames := map[string]bool{}
+	for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		containerNames[container.Name] = true
+	}
+	if sidecar.InitContainers, err = patcher.resolveContainerCollisions(pod.Spec.InitContainers, sidecar, sidecar.InitContainers, containerNames, renamedVolumes); err != nil {
+		return sidecar, err
+	}
+	if sidecar.Containers, err = patcher.resolveContainerCollisions(pod.Spec.Containers, sidecar, sidecar.Containers, containerNames, renamedVolumes); err != nil {
+		return sidecar, err
+	}
+
+	var pullSecrets []corev1.LocalObjectReference
+	for _, pullSecret := range sidecar.ImagePullSecrets {
+		if !containsPullSecret(pod.Spec.ImagePullSecrets, pullSecret.Name) {
+			pullSecrets = append(pullSecrets, pullSecret)
+		}
+	}
+	if sidecar.ImagePullSecrets != nil {
+		sidecar.ImagePullSecrets = pullSecrets
+	}
+	return sidecar, nil
+}
+
+func (patcher *SidecarInjectorPatcher) resolveContainerCollisions(existing []corev1.Container, sidecar Sidecar, containers []corev1.Container, names map[string]bool, renamedVolumes map[string]string) ([]corev1.Container, error) {
+	if containers == nil {
+		return nil, nil
+	}
+	var resolved []corev1.Container
+	for _, container := range containers {
+		container = renameVolumeMounts(container, renamedVolumes)
+		if names[container.Name] && !sidecar.replaces[container.Name] {
+			if index := containerIndex(existing, container.Name); index >= 0 && equality.Semantic.DeepEqual(existing[index], container) {
+				continue
+			}
+			renamed, err := patcher.renameColliding("container", sidecar, container.Name, names)
+			if err != nil {
+				return nil, err
+			}
+			container.Name = renamed
+		}
+		resolved = append(resolved, container)
+	}
+	return resolved, nil
+}
+
+// renameColliding Renames a colliding name according to the policy
+func (patcher *SidecarInjectorPatcher) renameColliding(kind string, sidecar Sidecar, name string, names map[string]bool) (string, error) {
+	if patcher.NameCollisionPolicy != NameCollisionPolicyRename {
+		return "", fmt.Errorf("%s %s is already defined differently in the pod", kind, name)
+	}
+	renamed := renamedName(sidecar, name)
+	if names[renamed] {
+		return "", fmt.Errorf("%s %s cannot be renamed to %s which is already defined in the pod", kind, name, renamed)
+	}
+	names[renamed] = true
+	return renamed, nil
+}
+
+// renameVolumeMounts Points the volume mounts and devices of the container to the renamed volumes
+func renameVolumeMounts(container corev1.Container, renamedVolumes map[string]string) corev1.Container {
+	if len(renamedVolumes) == 0 {
+		return container
+	}
+	container.VolumeMounts = append([]corev1.VolumeMount(nil), container.VolumeMounts...)
+	for index, mount := range container.VolumeMounts {
+		if renamed, ok := renamedVolumes[mount.Name]; ok {
+			container.VolumeMounts[index].Name = renamed
+		}
+	}
+	container.VolumeDevices = append([]corev1.VolumeDevice(nil), container.VolumeDevices...)
+	for index, device := range container.VolumeDevices {
+		if renamed, ok := renamedVolumes[device.Name]; ok {
+			container.VolumeDevices[index].Name = renamed
+		}
+	}
+	return container
+}
+
+func volumeIndex(volumes []corev1.Volume, name string) int {
+	for index, volume := range volumes {
+		if volume.Name == name {
+			return index
+		}
+	}
+	return -1
+}
+
+func containsPullSecret(pullSecrets []corev1.LocalObjectReference, name string) bool {
+	for _, pullSecret := range pullSecrets {
+		if pullSecret.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSidecarInjectorPatcher_resolveNameCollisions(t *testing.T) {
+	emptyDir := v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+	configMap := v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "config"}}}
+	pod := &v1.Pod{Spec: v1.PodSpec{
+		InitContainers:   []v1.Container{{Name: "init", Image: "busybox"}},
+		Containers:       []v1.Container{{Name: "app", Image: "app"}, {Name: "agent", Image: "agent", VolumeMounts: []v1.VolumeMount{{Name: "data", MountPath: "/data"}}}},
+		Volumes:          []v1.Volume{{Name: "data", VolumeSource: emptyDir}},
+		ImagePullSecrets: []v1.LocalObjectReference{{Name: "registry"}},
+	}}
+	tests := []struct {
+		name    string
+		policy  string
+		sidecar Sidecar
+		want    Sidecar
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "no collision",
+			policy:  NameCollisionPolicyFail,
+			sidecar: Sidecar{Name: "logs", Containers: []v1.Container{{Name: "logs"}}, Volumes: []v1.Volume{{Name: "logs", VolumeSource: emptyDir}}},
+			want:    Sidecar{Name: "logs", Containers: []v1.Container{{Name: "logs"}}, Volumes: []v1.Volume{{Name: "logs", VolumeSource: emptyDir}}},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "identical definitions deduplicated",
+			policy: NameCollisionPolicyFail,
+			sidecar: Sidecar{
+				Name:             "agent",
+				Containers:       []v1.Container{{Name: "agent", Image: "agent", VolumeMounts: []v1.VolumeMount{{Name: "data", MountPath: "/data"}}}},
+				Volumes:          []v1.Volume{{Name: "data", VolumeSource: emptyDir}},
+				ImagePullSecrets: []v1.LocalObjectReference{{Name: "registry"}},
+			},
+			want:    Sidecar{Name: "agent"},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "differing container denied",
+			policy:  NameCollisionPolicyFail,
+			sidecar: Sidecar{Name: "busybox", InitContainers: []v1.Container{{Name: "init", Image: "alpine"}}},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "differing volume denied",
+			policy:  NameCollisionPolicyFail,
+			sidecar: Sidecar{Name: "config", Volumes: []v1.Volume{{Name: "data", VolumeSource: configMap}}},
+			wantErr: assert.Error,
+		},
+		{
+			name:   "differing definitions renamed",
+			policy: NameCollisionPolicyRename,
+			sidecar: Sidecar{
+				Name:       "config",
+				Containers: []v1.Container{{Name: "app", Image: "config", VolumeMounts: []v1.VolumeMount{{Name: "data", MountPath: "/config"}}}},
+				Volumes:    []v1.Volume{{Name: "data", VolumeSource: configMap}},
+			},
+			want: Sidecar{
+				Name:       "config",
+				Containers: []v1.Container{{Name: "config-app", Image: "config", VolumeMounts: []v1.VolumeMount{{Name: "config-data", MountPath: "/config"}}}},
+				Volumes:    []v1.Volume{{Name: "config-data", VolumeSource: configMap}},
+			},
+			wantErr: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{NameCollisionPolicy: tt.policy}
+			got, err := patcher.resolveNameCollisions(pod, tt.sidecar)
+			if !tt.wantErr(t, err, "resolveNameCollisions(%v)", tt.sidecar.Name) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "resolveNameCollisions(%v)", tt.sidecar.Name)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+const (
+	// ConflictStrategySkip Pod containers with the name of a sidecar container are kept and the sidecar container is not injected
+	ConflictStrategySkip = "skip"
+	// ConflictStrategyReplace Pod containers with the name of a sidecar container are replaced by it
+	ConflictStrategyReplace = "replace"
+	// ConflictStrategyMerge Sidecar containers are strategic merged into the pod containers with the same name
+	ConflictStrategyMerge = "merge"
+	// ConflictStrategyFail Pods with containers with the name of a sidecar container are denied
+	ConflictStrategyFail = "fail"
+)
+
+// resolveContainerConflicts Applies the conflict strategy of the sidecar to its containers and init containers named
+// like a container of the pod, marking the ones taking over the pod container
+func resolveContainerConflicts(pod *corev1.Pod, sidecar Sidecar) (Sidecar, error) {
+	if sidecar.ConflictStrategy == "" {
+		return sidecar, nil
+	}
+	var err error
+	sidecar.replaces = map[string]bool{}
+	if sidecar.InitContainers, err = resolveConflicts(pod.Spec.InitContainers, sidecar, sidecar.InitContainers); err != nil {
+		return sidecar, err
+	}
+	if sidecar.Containers, err = resolveConflicts(pod.Spec.Containers, sidecar, sidecar.Containers); err != nil {
+		return sidecar, err
+	}
+	return sidecar, nil
+}
+
+func resolveConflicts(existing []corev1.Container, sidecar Sidecar, containers []corev1.Container) ([]corev1.Container, error) {
+	if containers == nil {
+		return nil, nil
+	}
+	resolved := make([]corev1.Container, 0, len(containers))
+	for _, container := range containers {
+		index := containerIndex(existing, container.Name)
+		if index < 0 {
+			resolved = append(resolved, container)
+			continue
+		}
+		switch sidecar.ConflictStrategy {
+		case ConflictStrategySkip:
+			continue
+		case ConflictStrategyFail:
+			return nil, fmt.Errorf("container %s already exists in the pod", container.Name)
+		case ConflictStrategyReplace:
+		case ConflictStrategyMerge:
+			var err error
+			if container, err = mergeContainer(existing[index], container); err != nil {
+				return nil, fmt.Errorf("merging container %s: %v", container.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown conflict strategy %s", sidecar.ConflictStrategy)
+		}
+		sidecar.replaces[container.Name] = true
+		resolved = append(resolved, container)
+	}
+	return resolved, nil
+}
+
+// mergeContainer Strategic merge of the sidecar container into the pod container
+func mergeContainer(existing corev1.Container, container corev1.Container) (corev1.Container, error) {
+	original, err := json.Marshal(existing)
+	if err != nil {
+		return container, err
+	}
+	patch, err := json.Marshal(container)
+	if err != nil {
+		return container, err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, corev1.Container{})
+	if err != nil {
+		return container, err
+	}
+	var result corev1.Container
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return container, err
+	}
+	return result, nil
+}
+
+// replaceContainerPatches Replaces the pod containers taken over by the sidecar, returning the sidecar without them
+func replaceContainerPatches(pod *corev1.Pod, sidecar Sidecar) (Sidecar, []admission.PatchOperation) {
+	if len(sidecar.replaces) == 0 {
+		return sidecar, nil
+	}
+	var patches []admission.PatchOperation
+	sidecar.InitContainers, patches = replaceContainers(pod.Spec.InitContainers, sidecar.replaces, sidecar.InitContainers, "/spec/initContainers", patches)
+	sidecar.Containers, patches = replaceContainers(pod.Spec.Containers, sidecar.replaces, sidecar.Containers, "/spec/containers", patches)
+	return sidecar, patches
+}
+
+func replaceContainers(existing []corev1.Container, replaces map[string]bool, containers []corev1.Container, path string, patches []admission.PatchOperation) ([]corev1.Container, []admission.PatchOperation) {
+	var remaining []corev1.Container
+	for _, container := range containers {
+		index := containerIndex(existing, container.Name)
+		if !replaces[container.Name] || index < 0 {
+			remaining = append(remaining, container)
+			continue
+		}
+		patches = append(patches, admission.PatchOperation{Op: "replace", Path: fmt.Sprintf("%s/%d", path, index), Value: container})
+		existing[index] = container
+	}
+	return remaining, patches
+}
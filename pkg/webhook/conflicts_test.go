@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSidecarInjectorPatcher_PatchPodCreateConflictStrategy(t *testing.T) {
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "fluentd"},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{
+			{Name: "app"},
+			{
+				Name:         "fluentd",
+				Image:        "fluentd:1.0",
+				Env:          []v1.EnvVar{{Name: "APP", Value: "my-app"}},
+				VolumeMounts: []v1.VolumeMount{{Name: "logs", MountPath: "/logs"}},
+			},
+		}},
+	}
+	tests := []struct {
+		strategy string
+		want     []admission.PatchOperation
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			strategy: ConflictStrategySkip,
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "fluentd-exporter"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			strategy: ConflictStrategyReplace,
+			want: []admission.PatchOperation{
+				{Op: "replace", Path: "/spec/containers/1", Value: v1.Container{
+					Name:         "fluentd",
+					Image:        "fluentd:2.0",
+					VolumeMounts: []v1.VolumeMount{{Name: "config", MountPath: "/config"}},
+				}},
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "fluentd-exporter"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			strategy: ConflictStrategyMerge,
+			want: []admission.PatchOperation{
+				{Op: "replace", Path: "/spec/containers/1", Value: v1.Container{
+					Name:         "fluentd",
+					Image:        "fluentd:2.0",
+					Env:          []v1.EnvVar{{Name: "APP", Value: "my-app"}},
+					VolumeMounts: []v1.VolumeMount{{Name: "config", MountPath: "/config"}, {Name: "logs", MountPath: "/logs"}},
+				}},
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "fluentd-exporter"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			strategy: ConflictStrategyFail,
+			wantErr:  assert.Error,
+		},
+		{
+			strategy: "overwrite",
+			wantErr:  assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			configmap := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "fluentd", Namespace: "test"},
+				Data: map[string]string{"sidecars.yaml": `
+                     - name: fluentd
+                       conflictStrategy: ` + tt.strategy + `
+                       containers:
+                         - name: fluentd
+                           image: fluentd:2.0
+                           volumeMounts:
+                             - name: config
+                               mountPath: /config
+                         - name: fluentd-exporter`,
+				},
+			}
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:      fake.NewSimpleClientset(configmap),
+				InjectPrefix:   "sidecar-injector.expedia.com",
+				InjectName:     "inject",
+				SidecarDataKey: "sidecars.yaml",
+			}
+			got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+			if !tt.wantErr(t, err, "PatchPodCreate()") {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
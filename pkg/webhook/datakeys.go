@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gzipMagic Leading bytes of gzip compressed content
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// matchesDataKey Whether the ConfigMap key matches the sidecar data key or pattern, ignoring a `.gz` suffix
+func (patcher *SidecarInjectorPatcher) matchesDataKey(key string) bool {
+	for _, candidate := range []string{key, strings.TrimSuffix(key, ".gz")} {
+		if matched, err := path.Match(patcher.SidecarDataKey, candidate); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// configmapDefinitions Parses the sidecars of all ConfigMap keys matching the sidecar data key, in key order
+func (patcher *SidecarInjectorPatcher) configmapDefinitions(configmap *corev1.ConfigMap) ([]Sidecar, error) {
+	contents := map[string][]byte{}
+	for key, value := range configmap.Data {
+		if patcher.matchesDataKey(key) {
+			contents[key] = []byte(value)
+		}
+	}
+	for key, value := range configmap.BinaryData {
+		if patcher.matchesDataKey(key) {
+			contents[key] = value
+		}
+	}
+	keys := make([]string, 0, len(contents))
+	for key := range contents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sidecars []Sidecar
+	for _, key := range keys {
+		content, err := decompress(contents[key])
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", key, err)
+		}
+		keySidecars, err := parseSidecarDocuments(content)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", key, err)
+		}
+		sidecars = append(sidecars, keySidecars...)
+	}
+	return sidecars, nil
+}
+
+// maxDecompressedSize Limit on decompressed sidecar definitions, well above the 1MiB a ConfigMap can hold
+const maxDecompressedSize = 8 << 20
+
+// decompress Decompresses gzip compressed content, other content is returned as is
+func decompress(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, gzipMagic) {
+		return content, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed content exceeds %d bytes", maxDecompressedSize)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func gzipped(t *testing.T, content string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestSidecarInjectorPatcher_configmapDefinitions(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		Data: map[string]string{
+			"b.sidecars.yaml": "- name: logs\n---\nname: metrics\n---\n",
+			"a.sidecars.yaml": `[{"name": "envoy"}]`,
+			"c.sidecars.yaml": `{"name": "otel"}`,
+			"README.md":       `not a sidecar`,
+		},
+		BinaryData: map[string][]byte{
+			"d.sidecars.yaml.gz": gzipped(t, "- name: vault"),
+		},
+	}
+	tests := []struct {
+		name    string
+		dataKey string
+		want    []string
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{name: "pattern", dataKey: "*.sidecars.yaml", want: []string{"envoy", "logs", "metrics", "otel", "vault"}, wantErr: assert.NoError},
+		{name: "single key", dataKey: "c.sidecars.yaml", want: []string{"otel"}, wantErr: assert.NoError},
+		{name: "no matching key", dataKey: "sidecars.yaml", wantErr: assert.NoError},
+		{name: "invalid content", dataKey: "*.md", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{SidecarDataKey: tt.dataKey}
+			sidecars, err := patcher.configmapDefinitions(configmap)
+			if !tt.wantErr(t, err, "configmapDefinitions(%v)", tt.dataKey) || err != nil {
+				return
+			}
+			var got []string
+			for _, sidecar := range sidecars {
+				got = append(got, sidecar.Name)
+			}
+			assert.Equalf(t, tt.want, got, "configmapDefinitions(%v)", tt.dataKey)
+		})
+	}
+}
+
+func Test_decompress(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    []byte
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{name: "plain", content: []byte("- name: envoy"), want: []byte("- name: envoy"), wantErr: assert.NoError},
+		{name: "gzip", content: gzipped(t, "- name: envoy"), want: []byte("- name: envoy"), wantErr: assert.NoError},
+		{name: "gzip bomb", content: gzipped(t, strings.Repeat("\n", maxDecompressedSize+1)), wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decompress(tt.content)
+			if !tt.wantErr(t, err, "decompress()") || err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+)
+
+const (
+	// SunsetActionDeny Pods referencing a sidecar past its sunset date are denied
+	SunsetActionDeny = "deny"
+	// SunsetActionReplace Sidecars past their sunset date are substituted by their replacement
+	SunsetActionReplace = "replace"
+)
+
+// parseSunset Parses the sunset of a sidecar as a date or a timestamp
+func parseSunset(sunset string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", sunset); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, sunset)
+}
+
+// checkDeprecation Warns about deprecated sidecars, returning the replacement to inject once a sidecar is past its sunset
+func (patcher *SidecarInjectorPatcher) checkDeprecation(ctx context.Context, sidecar Sidecar) (*sidecarReference, error) {
+	if !sidecar.Deprecated {
+		return nil, nil
+	}
+	deprecatedInjections.WithLabelValues(patcher.metricLabel(MetricsLabelSidecar, sidecar.Name)).Inc()
+	message := fmt.Sprintf("sidecar %s from configmap %s is deprecated", sidecar.Name, sidecar.configmap)
+	if sidecar.Replacement != "" {
+		message += fmt.Sprintf(", use %s instead", sidecar.Replacement)
+	}
+	if sidecar.Sunset == "" {
+		admission.AddWarning(ctx, message)
+		return nil, nil
+	}
+	sunset, err := parseSunset(sidecar.Sunset)
+	if err != nil {
+		return nil, fmt.Errorf("sidecar %s from configmap %s has an invalid sunset %q: %v", sidecar.Name, sidecar.configmap, sidecar.Sunset, err)
+	}
+	if time.Now().Before(sunset) {
+		admission.AddWarning(ctx, fmt.Sprintf("%s and will be removed on %s", message, sidecar.Sunset))
+		return nil, nil
+	}
+	if patcher.SunsetAction != SunsetActionReplace || sidecar.Replacement == "" {
+		return nil, fmt.Errorf("sidecar %s from configmap %s was removed on %s", sidecar.Name, sidecar.configmap, sidecar.Sunset)
+	}
+	admission.AddWarning(ctx, fmt.Sprintf("sidecar %s from configmap %s was removed on %s, injecting %s instead", sidecar.Name, sidecar.configmap, sidecar.Sunset, sidecar.Replacement))
+	replacement, err := parseSidecarReference(sidecar.Replacement)
+	if err != nil {
+		return nil, fmt.Errorf("sidecar %s from configmap %s has an invalid replacement: %v", sidecar.Name, sidecar.configmap, err)
+	}
+	return &replacement, nil
+}
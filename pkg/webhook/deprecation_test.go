@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSidecarInjectorPatcher_checkDeprecation(t *testing.T) {
+	tests := []struct {
+		name            string
+		sunsetAction    string
+		sidecar         Sidecar
+		wantReplacement *sidecarReference
+		wantWarnings    []string
+		wantErr         assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "not deprecated",
+			sidecar: Sidecar{Name: "agent", configmap: "agent"},
+			wantErr: assert.NoError,
+		},
+		{
+			name:         "deprecated without sunset",
+			sidecar:      Sidecar{Name: "agent", configmap: "agent", Deprecated: true, Replacement: "otel"},
+			wantWarnings: []string{"sidecar agent from configmap agent is deprecated, use otel instead"},
+			wantErr:      assert.NoError,
+		},
+		{
+			name:         "deprecated before sunset",
+			sidecar:      Sidecar{Name: "agent", configmap: "agent", Deprecated: true, Sunset: "2999-01-01"},
+			wantWarnings: []string{"sidecar agent from configmap agent is deprecated and will be removed on 2999-01-01"},
+			wantErr:      assert.NoError,
+		},
+		{
+			name:         "past sunset denied",
+			sunsetAction: SunsetActionDeny,
+			sidecar:      Sidecar{Name: "agent", configmap: "agent", Deprecated: true, Replacement: "otel", Sunset: "2000-01-01"},
+			wantErr:      assert.Error,
+		},
+		{
+			name:         "past sunset without replacement",
+			sunsetAction: SunsetActionReplace,
+			sidecar:      Sidecar{Name: "agent", configmap: "agent", Deprecated: true, Sunset: "2000-01-01T00:00:00Z"},
+			wantErr:      assert.Error,
+		},
+		{
+			name:            "past sunset replaced",
+			sunsetAction:    SunsetActionReplace,
+			sidecar:         Sidecar{Name: "agent", configmap: "agent", Deprecated: true, Replacement: "otel@1.0.0", Sunset: "2000-01-01"},
+			wantReplacement: &sidecarReference{Name: "otel", Version: "1.0.0"},
+			wantWarnings:    []string{"sidecar agent from configmap agent was removed on 2000-01-01, injecting otel@1.0.0 instead"},
+			wantErr:         assert.NoError,
+		},
+		{
+			name:    "invalid sunset",
+			sidecar: Sidecar{Name: "agent", configmap: "agent", Deprecated: true, Sunset: "next year"},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{SunsetAction: tt.sunsetAction}
+			ctx := admission.WithWarnings(context.Background())
+			got, err := patcher.checkDeprecation(ctx, tt.sidecar)
+			if !tt.wantErr(t, err, "checkDeprecation(%v)", tt.sidecar) {
+				return
+			}
+			assert.Equalf(t, tt.wantReplacement, got, "checkDeprecation(%v)", tt.sidecar)
+			assert.Equalf(t, tt.wantWarnings, admission.Warnings(ctx), "checkDeprecation(%v)", tt.sidecar)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateSunsetReplacement(t *testing.T) {
+	configmaps := []*v1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "haystack", Namespace: "test"},
+			Data: map[string]string{"sidecars.yaml": `
+                     - name: haystack-agent
+                       deprecated: true
+                       replacement: otel
+                       sunset: "2000-01-01"
+                       labels:
+                         agent: haystack`,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "otel", Namespace: "test"},
+			Data: map[string]string{"sidecars.yaml": `
+                     - name: otel-agent
+                       labels:
+                         agent: otel`,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cycle", Namespace: "test"},
+			Data: map[string]string{"sidecars.yaml": `
+                     - name: cycle
+                       deprecated: true
+                       replacement: cycle
+                       sunset: "2000-01-01"`,
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		reference  string
+		wantLabels interface{}
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{name: "replacement injected", reference: "haystack", wantLabels: map[string]string{"agent": "otel"}, wantErr: assert.NoError},
+		{name: "replacement cycle", reference: "cycle", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:      fake.NewSimpleClientset(configmaps[0], configmaps[1], configmaps[2]),
+				InjectPrefix:   "sidecar-injector.expedia.com",
+				InjectName:     "inject",
+				SidecarDataKey: "sidecars.yaml",
+				SunsetAction:   SunsetActionReplace,
+			}
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"sidecar-injector.expedia.com/inject": tt.reference},
+			}}
+			got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+			if !tt.wantErr(t, err, "PatchPodCreate(%v)", tt.reference) || err != nil {
+				return
+			}
+			assert.Len(t, got, 1)
+			assert.Equal(t, tt.wantLabels, got[0].Value)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	eventReasonConfigMapNotFound     = "SidecarConfigMapNotFound"
+	eventReasonConfigMapInvalid      = "SidecarConfigMapInvalid"
+	eventReasonConfigMapLookupFailed = "SidecarConfigMapLookupFailed"
+	eventReasonInjectionFailed       = "SidecarInjectionFailed"
+	eventReasonInjectionWarning      = "SidecarInjectionWarning"
+)
+
+// configmapEvent Records an event on a sidecar ConfigMap, which may not exist
+func (patcher *SidecarInjectorPatcher) configmapEvent(namespace string, name string, reason string, message string) {
+	if patcher.EventRecorder == nil {
+		return
+	}
+	patcher.EventRecorder.Event(&corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  namespace,
+		Name:       name,
+	}, corev1.EventTypeWarning, reason, message)
+}
+
+// sidecarEvents Records the failure of a sidecar and the warnings added since the given count on its ConfigMap, so that
+// the owners of the sidecar see them whether or not the pod has a controller
+func (patcher *SidecarInjectorPatcher) sidecarEvents(ctx context.Context, namespace string, sidecar Sidecar, warnings int, err error) {
+	if patcher.EventRecorder == nil || sidecar.configmap == "" {
+		return
+	}
+	for _, warning := range admission.Warnings(ctx)[warnings:] {
+		patcher.configmapEvent(namespace, sidecar.configmap, eventReasonInjectionWarning, warning)
+	}
+	if err != nil {
+		patcher.configmapEvent(namespace, sidecar.configmap, eventReasonInjectionFailed, fmt.Sprintf("pod denied: %v", err))
+	}
+}
+
+// workloadReference Reference to the controller of the pod, which unlike the pod already exists at admission
+func workloadReference(namespace string, pod corev1.Pod) *corev1.ObjectReference {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return nil
+	}
+	return &corev1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Namespace:  namespace,
+		Name:       owner.Name,
+		UID:        owner.UID,
+	}
+}
+
+// injectionEvents Records the failure and the warnings of an injection on the workload of the pod
+func (patcher *SidecarInjectorPatcher) injectionEvents(ctx context.Context, namespace string, pod corev1.Pod, err error) {
+	if patcher.EventRecorder == nil {
+		return
+	}
+	workload := workloadReference(namespace, pod)
+	if workload == nil {
+		return
+	}
+	for _, warning := range admission.Warnings(ctx) {
+		patcher.EventRecorder.Event(workload, corev1.EventTypeWarning, eventReasonInjectionWarning, warning)
+	}
+	if err != nil {
+		patcher.EventRecorder.Event(workload, corev1.EventTypeWarning, eventReasonInjectionFailed, fmt.Sprintf("pod denied: %v", err))
+	}
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSidecarInjectorPatcher_PatchPodCreateEvents(t *testing.T) {
+	broken := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: "test"},
+		Data:       map[string]string{"sidecars.yaml": `name: [`},
+	}
+	envoy := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "envoy", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: envoy
+                       containers:
+                         - name: envoy
+                           image: envoyproxy/envoy:v1.29`,
+		},
+	}
+	controller := true
+	owner := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-5d8f", UID: "1234", Controller: &controller}
+	tests := []struct {
+		name       string
+		owners     []metav1.OwnerReference
+		registries []string
+		annotation string
+		want       []string
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:       "missing configmap",
+			owners:     []metav1.OwnerReference{owner},
+			annotation: "missing",
+			want: []string{
+				"Warning SidecarConfigMapNotFound sidecar missing referenced by a pod was not found involvedObject{kind=ConfigMap,apiVersion=v1}",
+				"Warning SidecarInjectionWarning sidecar configmap test/missing was not found involvedObject{kind=ReplicaSet,apiVersion=apps/v1}",
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:       "broken configmap",
+			owners:     []metav1.OwnerReference{owner},
+			annotation: "broken",
+			want: []string{
+				"Warning SidecarConfigMapInvalid error unmarshalling sidecars - key sidecars.yaml: error converting YAML to JSON: yaml: line 1: did not find expected node content involvedObject{kind=ConfigMap,apiVersion=v1}",
+				"Warning SidecarInjectionWarning skipping sidecar configmap test/broken with invalid definitions involvedObject{kind=ReplicaSet,apiVersion=apps/v1}",
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:       "denied pod",
+			owners:     []metav1.OwnerReference{owner},
+			annotation: "envoy(mode=debug)",
+			want: []string{
+				"Warning SidecarInjectionFailed pod denied: sidecar configmap test/envoy: unknown parameter mode involvedObject{kind=ConfigMap,apiVersion=v1}",
+				"Warning SidecarInjectionFailed pod denied: sidecar configmap test/envoy: unknown parameter mode involvedObject{kind=ReplicaSet,apiVersion=apps/v1}",
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name:       "denied image without controller",
+			registries: []string{"registry.example.com"},
+			annotation: "envoy",
+			want: []string{
+				"Warning SidecarInjectionFailed pod denied: sidecar envoy from configmap test/envoy rejected by image policy: container envoy: image envoyproxy/envoy:v1.29 is not from an allowed registry [registry.example.com] involvedObject{kind=ConfigMap,apiVersion=v1}",
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name:       "pod without controller",
+			annotation: "missing",
+			want: []string{
+				"Warning SidecarConfigMapNotFound sidecar missing referenced by a pod was not found involvedObject{kind=ConfigMap,apiVersion=v1}",
+			},
+			wantErr: assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			recorder.IncludeObject = true
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:              fake.NewSimpleClientset(broken, envoy),
+				InjectPrefix:           "sidecar-injector.expedia.com",
+				InjectName:             "inject",
+				SidecarDataKey:         "sidecars.yaml",
+				AllowedImageRegistries: tt.registries,
+				EventRecorder:          recorder,
+			}
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				GenerateName:    "app-5d8f-",
+				OwnerReferences: tt.owners,
+				Annotations:     map[string]string{"sidecar-injector.expedia.com/inject": tt.annotation},
+			}}
+			_, err := patcher.PatchPodCreate(admission.WithWarnings(context.Background()), "test", pod)
+			tt.wantErr(t, err, "PatchPodCreate()")
+			close(recorder.Events)
+			var got []string
+			for event := range recorder.Events {
+				got = append(got, event)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SidecarPerContainer Instantiates the sidecar once for each matching application container
+type SidecarPerContainer struct {
+	Containers []string `yaml:"containers"`
+}
+
+// templateContainer Application container a per container sidecar is instantiated for
+type templateContainer struct {
+	Name  string
+	Image string
+	Ports []corev1.ContainerPort
+}
+
+// templateData Data the string fields of a sidecar are rendered with
+type templateData struct {
+	Container templateContainer
+	Params    map[string]string
+}
+
+// renderSidecar Copy of the sidecar with all its string fields rendered as templates
+func renderSidecar(sidecar Sidecar, data templateData) (Sidecar, error) {
+	encoded, err := json.Marshal(sidecar)
+	if err != nil {
+		return sidecar, err
+	}
+	var rendered Sidecar
+	if err := json.Unmarshal(encoded, &rendered); err != nil {
+		return sidecar, err
+	}
+	rendered.configmap, rendered.inline = sidecar.configmap, sidecar.inline
+	if err := renderStrings(reflect.ValueOf(&rendered).Elem(), data); err != nil {
+		return sidecar, err
+	}
+	return rendered, nil
+}
+
+// renderStrings Renders the settable strings of the value as templates
+func renderStrings(value reflect.Value, data templateData) error {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			return renderStrings(value.Elem(), data)
+		}
+	case reflect.Struct:
+		for index := 0; index < value.NumField(); index++ {
+			if field := value.Field(index); field.CanSet() {
+				if err := renderStrings(field, data); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice:
+		for index := 0; index < value.Len(); index++ {
+			if err := renderStrings(value.Index(index), data); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range value.MapKeys() {
+			rendered, err := renderString(value.MapIndex(key).String(), data)
+			if err != nil {
+				return err
+			}
+			value.SetMapIndex(key, reflect.ValueOf(rendered).Convert(value.Type().Elem()))
+		}
+	case reflect.String:
+		rendered, err := renderString(value.String(), data)
+		if err != nil {
+			return err
+		}
+		value.SetString(rendered)
+	}
+	return nil
+}
+
+func renderString(text string, data templateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("sidecar").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// expandSidecar Instances of the sidecar to inject rendered with its parameters, one for each matching application
+// container of a per container sidecar
+func expandSidecar(pod corev1.Pod, sidecar Sidecar, params map[string]string) ([]Sidecar, error) {
+	if sidecar.PerContainer == nil {
+		if len(sidecar.Parameters) == 0 {
+			return []Sidecar{sidecar}, nil
+		}
+		instance, err := renderSidecar(sidecar, templateData{Params: params})
+		if err != nil {
+			return nil, fmt.Errorf("rendering sidecar %s: %v", sidecar.Name, err)
+		}
+		return []Sidecar{instance}, nil
+	}
+	var instances []Sidecar
+	names := map[string]string{}
+	for _, application := range pod.Spec.Containers {
+		if len(sidecar.PerContainer.Containers) > 0 && !slices.Contains(sidecar.PerContainer.Containers, application.Name) {
+			continue
+		}
+		instance, err := renderSidecar(sidecar, templateData{Container: templateContainer{
+			Name:  application.Name,
+			Image: application.Image,
+			Ports: application.Ports,
+		}, Params: params})
+		if err != nil {
+			return nil, fmt.Errorf("rendering sidecar %s for container %s: %v", sidecar.Name, application.Name, err)
+		}
+		for _, name := range instanceNames(instance) {
+			if other, ok := names[name]; ok {
+				return nil, fmt.Errorf("sidecar %s generates %s for both containers %s and %s", sidecar.Name, name, other, application.Name)
+			}
+			names[name] = application.Name
+		}
+		instance.PerContainer = nil
+		instance.application = application.Name
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// instanceNames Names of the containers and volumes of a sidecar instance, which must be unique in the pod
+func instanceNames(sidecar Sidecar) []string {
+	var names []string
+	for _, container := range append(append([]corev1.Container{}, sidecar.InitContainers...), sidecar.Containers...) {
+		names = append(names, "container "+container.Name)
+	}
+	for _, volume := range sidecar.Volumes {
+		names = append(names, "volume "+volume.Name)
+	}
+	return names
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_expandSidecar(t *testing.T) {
+	pod := v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{
+		{Name: "api", Image: "api:1.0", Ports: []v1.ContainerPort{{ContainerPort: 8080}}},
+		{Name: "worker", Image: "worker:1.0"},
+	}}}
+	tests := []struct {
+		name    string
+		sidecar Sidecar
+		want    []Sidecar
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "not per container",
+			sidecar: Sidecar{Name: "logs", Containers: []v1.Container{{Name: "{{ .Container.Name }}"}}},
+			want:    []Sidecar{{Name: "logs", Containers: []v1.Container{{Name: "{{ .Container.Name }}"}}}},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "all containers",
+			sidecar: Sidecar{
+				Name:         "logs",
+				PerContainer: &SidecarPerContainer{},
+				Containers:   []v1.Container{{Name: "logs-{{ .Container.Name }}", Args: []string{"--image={{ .Container.Image }}"}}},
+				Annotations:  map[string]string{"logs/{{ .Container.Name }}": "{{ .Container.Name }}"},
+			},
+			want: []Sidecar{
+				{Name: "logs", Containers: []v1.Container{{Name: "logs-api", Args: []string{"--image=api:1.0"}}}, Annotations: map[string]string{"logs/{{ .Container.Name }}": "api"}, application: "api"},
+				{Name: "logs", Containers: []v1.Container{{Name: "logs-worker", Args: []string{"--image=worker:1.0"}}}, Annotations: map[string]string{"logs/{{ .Container.Name }}": "worker"}, application: "worker"},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "selected containers with ports",
+			sidecar: Sidecar{
+				Name:         "probe",
+				PerContainer: &SidecarPerContainer{Containers: []string{"api"}},
+				Containers:   []v1.Container{{Name: "probe-{{ .Container.Name }}", Args: []string{"{{ range .Container.Ports }}{{ .ContainerPort }}{{ end }}"}}},
+			},
+			want:    []Sidecar{{Name: "probe", Containers: []v1.Container{{Name: "probe-api", Args: []string{"8080"}}}, application: "api"}},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "duplicate names",
+			sidecar: Sidecar{Name: "logs", PerContainer: &SidecarPerContainer{}, Containers: []v1.Container{{Name: "logs"}}},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "invalid template",
+			sidecar: Sidecar{Name: "logs", PerContainer: &SidecarPerContainer{}, Containers: []v1.Container{{Name: "{{ .Container.Missing }}"}}},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandSidecar(pod, tt.sidecar, nil)
+			if !tt.wantErr(t, err, "expandSidecar(%v)", tt.sidecar.Name) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "expandSidecar(%v)", tt.sidecar.Name)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreatePerContainer(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: logs
+                       perContainer: {}
+                       containers:
+                         - name: "logs-{{ .Container.Name }}"
+                           volumeMounts:
+                             - name: "logs-{{ .Container.Name }}"
+                               mountPath: /logs
+                       volumes:
+                         - name: "logs-{{ .Container.Name }}"
+                           emptyDir: {}`,
+		},
+	}
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "logs"},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "api"}, {Name: "worker"}}},
+	}
+	patcher := &SidecarInjectorPatcher{
+		K8sClient:      fake.NewSimpleClientset(configmap),
+		InjectPrefix:   "sidecar-injector.expedia.com",
+		InjectName:     "inject",
+		SidecarDataKey: "sidecars.yaml",
+	}
+	got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+	assert.NoError(t, err)
+	container := func(name string) v1.Container {
+		return v1.Container{Name: name, VolumeMounts: []v1.VolumeMount{{Name: name, MountPath: "/logs"}}}
+	}
+	volume := func(name string) v1.Volume {
+		return v1.Volume{Name: name, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}
+	}
+	assert.Equal(t, []admission.PatchOperation{
+		{Op: "add", Path: "/spec/containers/-", Value: container("logs-api")},
+		{Op: "add", Path: "/spec/volumes", Value: []v1.Volume{volume("logs-api")}},
+		{Op: "add", Path: "/spec/containers/-", Value: container("logs-worker")},
+		{Op: "add", Path: "/spec/volumes/-", Value: volume("logs-worker")},
+	}, got)
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreatePerContainerAutoPorts(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "tail", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: tail
+                       perContainer: {}
+                       autoPorts:
+                         - name: admin
+                       containers:
+                         - name: "tail-{{ .Container.Name }}"
+                           ports:
+                             - name: admin`,
+		},
+	}
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "tail"},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "a"}, {Name: "b"}}},
+	}
+	patcher := &SidecarInjectorPatcher{
+		K8sClient:      fake.NewSimpleClientset(configmap),
+		InjectPrefix:   "sidecar-injector.expedia.com",
+		InjectName:     "inject",
+		SidecarDataKey: "sidecars.yaml",
+		AutoPortRange:  PortRange{Min: 15000, Max: 15999},
+	}
+	got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+	assert.NoError(t, err)
+	container := func(name string, port int32) v1.Container {
+		return v1.Container{
+			Name:  name,
+			Ports: []v1.ContainerPort{{Name: "admin", ContainerPort: port}},
+			Env:   []v1.EnvVar{{Name: "TAIL_ADMIN_PORT", Value: fmt.Sprint(port)}},
+		}
+	}
+	assert.Equal(t, []admission.PatchOperation{
+		{Op: "add", Path: "/spec/containers/-", Value: container("tail-a", 15000)},
+		{Op: "add", Path: "/spec/containers/0/env", Value: []v1.EnvVar{{Name: "TAIL_ADMIN_PORT", Value: "15000"}}},
+		{Op: "add", Path: "/spec/containers/-", Value: container("tail-b", 15001)},
+		{Op: "add", Path: "/spec/containers/1/env", Value: []v1.EnvVar{{Name: "TAIL_ADMIN_PORT", Value: "15001"}}},
+	}, got)
+}
@@ -0,0 +1,198 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// extendsReference A base sidecar referenced by `extends`, e.g. `platform/otel-base:otel`
+type extendsReference struct {
+	Namespace string
+	ConfigMap string
+	Name      string
+}
+
+func (ref extendsReference) String() string {
+	return fmt.Sprintf("%s/%s:%s", ref.Namespace, ref.ConfigMap, ref.Name)
+}
+
+// parseExtendsReference Parses `[namespace/]configmap[:name]`, defaulting to the namespace and name of the extending sidecar
+func parseExtendsReference(extends string, namespace string, name string) (extendsReference, error) {
+	ref := extendsReference{Namespace: namespace, Name: name}
+	rest := strings.TrimSpace(extends)
+	if ns, configmap, ok := strings.Cut(rest, "/"); ok {
+		ref.Namespace, rest = ns, configmap
+	}
+	if configmap, sidecar, ok := strings.Cut(rest, ":"); ok {
+		rest, ref.Name = configmap, sidecar
+	}
+	ref.ConfigMap = rest
+	if ref.Namespace == "" || ref.ConfigMap == "" || ref.Name == "" {
+		return ref, fmt.Errorf("invalid extends reference %q", extends)
+	}
+	return ref, nil
+}
+
+// parseSidecars Parses the sidecar definitions, keeping each raw definition for sidecars extending them
+func parseSidecars(data []byte) ([]Sidecar, error) {
+	var sidecars []Sidecar
+	if err := yaml.Unmarshal(data, &sidecars); err != nil {
+		return nil, err
+	}
+	var definitions []map[string]interface{}
+	if err := yaml.Unmarshal(data, &definitions); err != nil {
+		return nil, err
+	}
+	for index := range sidecars {
+		sidecars[index].definition = definitions[index]
+	}
+	return sidecars, nil
+}
+
+// extendedSidecar Merges the sidecar over the base sidecar it extends, recursively
+func (patcher *SidecarInjectorPatcher) extendedSidecar(ctx context.Context, namespace string, configmap string, sidecar Sidecar, chain []string) (Sidecar, error) {
+	if sidecar.Extends == "" {
+		return sidecar, nil
+	}
+	ref, err := parseExtendsReference(sidecar.Extends, namespace, sidecar.Name)
+	if err != nil {
+		return sidecar, err
+	}
+	if ref.Namespace != namespace && !slices.Contains(patcher.ExtendsNamespaces, ref.Namespace) {
+		return sidecar, fmt.Errorf("sidecars from namespace %s cannot be extended", ref.Namespace)
+	}
+	chain = append(chain, extendsReference{Namespace: namespace, ConfigMap: configmap, Name: sidecar.Name}.String())
+	if slices.Contains(chain, ref.String()) {
+		return sidecar, fmt.Errorf("extends cycle %s -> %s", strings.Join(chain, " -> "), ref)
+	}
+	base, err := patcher.baseSidecar(ctx, ref)
+	if err != nil {
+		return sidecar, err
+	}
+	if base, err = patcher.extendedSidecar(ctx, ref.Namespace, ref.ConfigMap, base, chain); err != nil {
+		return sidecar, err
+	}
+	definition, err := mergeDefinitions(base.definition, sidecar.definition)
+	if err != nil {
+		return sidecar, fmt.Errorf("merging %s: %v", ref, err)
+	}
+	encoded, err := json.Marshal(definition)
+	if err != nil {
+		return sidecar, err
+	}
+	var extended Sidecar
+	if err := yaml.Unmarshal(encoded, &extended); err != nil {
+		return sidecar, fmt.Errorf("merging %s: %v", ref, err)
+	}
+	extended.definition = definition
+	return extended, nil
+}
+
+// baseSidecar Fetches the first sidecar with the referenced name
+func (patcher *SidecarInjectorPatcher) baseSidecar(ctx context.Context, ref extendsReference) (Sidecar, error) {
+	configmap, err := patcher.K8sClient.CoreV1().ConfigMaps(ref.Namespace).Get(ctx, ref.ConfigMap, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return Sidecar{}, fmt.Errorf("base sidecar %s was not found", ref)
+	} else if err != nil {
+		return Sidecar{}, fmt.Errorf("error fetching base sidecar %s - %v", ref, err)
+	}
+	sidecars, err := patcher.configmapDefinitions(configmap)
+	if err != nil {
+		return Sidecar{}, fmt.Errorf("error unmarshalling base sidecar %s - %v", ref, err)
+	}
+	for _, sidecar := range sidecars {
+		if sidecar.Name == ref.Name {
+			return sidecar, nil
+		}
+	}
+	return Sidecar{}, fmt.Errorf("base sidecar %s was not found", ref)
+}
+
+// mergeDefinitions Merges the raw sidecar definition over its base, merging containers, volumes and pull secrets by name
+// with strategic merge semantics and annotations and labels by key
+func mergeDefinitions(base map[string]interface{}, override map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	var err error
+	for key, value := range override {
+		switch key {
+		case "initContainers", "containers":
+			merged[key], err = mergeNamedList(merged[key], value, corev1.Container{})
+		case "volumes":
+			merged[key], err = mergeNamedList(merged[key], value, corev1.Volume{})
+		case "imagePullSecrets":
+			merged[key], err = mergeNamedList(merged[key], value, corev1.LocalObjectReference{})
+		case "annotations", "labels":
+			merged[key] = mergeMap(merged[key], value)
+		default:
+			merged[key] = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	delete(merged, "extends")
+	return merged, nil
+}
+
+// mergeNamedList Strategic merge of the items of two lists with the same name
+func mergeNamedList(base interface{}, override interface{}, dataStruct interface{}) (interface{}, error) {
+	baseItems, baseOk := base.([]interface{})
+	overrideItems, overrideOk := override.([]interface{})
+	if !baseOk || !overrideOk {
+		return override, nil
+	}
+	merged := append([]interface{}(nil), baseItems...)
+	for _, overrideItem := range overrideItems {
+		overrideMap, ok := overrideItem.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("item %v is not an object", overrideItem)
+		}
+		index := slices.IndexFunc(merged, func(item interface{}) bool {
+			itemMap, ok := item.(map[string]interface{})
+			return ok && itemMap["name"] == overrideMap["name"]
+		})
+		if index < 0 {
+			merged = append(merged, overrideMap)
+			continue
+		}
+		item, err := strategicpatch.StrategicMergeMapPatch(merged[index].(map[string]interface{}), overrideMap, dataStruct)
+		if err != nil {
+			return nil, err
+		}
+		merged[index] = map[string]interface{}(item)
+	}
+	return merged, nil
+}
+
+// mergeMap Merges two maps by key, null values removing the key
+func mergeMap(base interface{}, override interface{}) interface{} {
+	baseMap, baseOk := base.(map[string]interface{})
+	overrideMap, overrideOk := override.(map[string]interface{})
+	if !baseOk || !overrideOk {
+		return override
+	}
+	merged := make(map[string]interface{}, len(baseMap)+len(overrideMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overrideMap {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_parseExtendsReference(t *testing.T) {
+	tests := []struct {
+		extends string
+		want    extendsReference
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{extends: "otel-base", want: extendsReference{Namespace: "test", ConfigMap: "otel-base", Name: "otel"}, wantErr: assert.NoError},
+		{extends: "platform/otel-base", want: extendsReference{Namespace: "platform", ConfigMap: "otel-base", Name: "otel"}, wantErr: assert.NoError},
+		{extends: "platform/otel-base:collector", want: extendsReference{Namespace: "platform", ConfigMap: "otel-base", Name: "collector"}, wantErr: assert.NoError},
+		{extends: "platform/", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.extends, func(t *testing.T) {
+			got, err := parseExtendsReference(tt.extends, "test", "otel")
+			if !tt.wantErr(t, err, "parseExtendsReference(%v)", tt.extends) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "parseExtendsReference(%v)", tt.extends)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_configmapSidecarsExtends(t *testing.T) {
+	base := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "otel-base", Namespace: "platform"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: otel
+                       annotations:
+                         team: platform
+                         tier: base
+                       containers:
+                         - name: otel
+                           image: otel:1.0
+                           env:
+                             - name: LOG_LEVEL
+                               value: info
+                       volumes:
+                         - name: otel-config
+                           configMap:
+                             name: otel-config`,
+		},
+	}
+	tests := []struct {
+		name       string
+		sidecars   string
+		namespaces []string
+		want       []Sidecar
+	}{
+		{
+			name: "merged with base",
+			sidecars: `
+                     - name: otel
+                       extends: platform/otel-base
+                       annotations:
+                         tier: null
+                       containers:
+                         - name: otel
+                           image: otel:2.0
+                           env:
+                             - name: EXPORTER
+                               value: jaeger
+                         - name: otel-proxy`,
+			namespaces: []string{"platform"},
+			want: []Sidecar{{
+				Name:        "otel",
+				Annotations: map[string]string{"team": "platform"},
+				Containers: []v1.Container{
+					{Name: "otel", Image: "otel:2.0", Env: []v1.EnvVar{{Name: "EXPORTER", Value: "jaeger"}, {Name: "LOG_LEVEL", Value: "info"}}},
+					{Name: "otel-proxy"},
+				},
+				Volumes: []v1.Volume{{Name: "otel-config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "otel-config"}}}}},
+			}},
+		},
+		{
+			name: "namespace not allowed",
+			sidecars: `
+                     - name: otel
+                       extends: platform/otel-base`,
+		},
+		{
+			name: "missing base",
+			sidecars: `
+                     - name: otel
+                       extends: platform/otel-base:collector`,
+			namespaces: []string{"platform"},
+		},
+		{
+			name: "cycle",
+			sidecars: `
+                     - name: a
+                       extends: sidecars:b
+                     - name: b
+                       extends: sidecars:a
+                     - name: c`,
+			want: []Sidecar{{Name: "c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configmap := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "sidecars", Namespace: "test"},
+				Data:       map[string]string{"sidecars.yaml": tt.sidecars},
+			}
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:         fake.NewSimpleClientset(base, configmap),
+				SidecarDataKey:    "sidecars.yaml",
+				ExtendsNamespaces: tt.namespaces,
+			}
+			got := patcher.configmapSidecars(context.Background(), "test", "sidecars")
+			for index := range got {
+				got[index].definition = nil
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
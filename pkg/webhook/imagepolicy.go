@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultRegistry  = "docker.io"
+	defaultNamespace = "library"
+)
+
+// imageReference An image split into its fully qualified repository and its tag and/or digest
+type imageReference struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func (ref imageReference) String() string {
+	image := ref.Repository
+	if ref.Tag != "" {
+		image += ":" + ref.Tag
+	}
+	if ref.Digest != "" {
+		image += "@" + ref.Digest
+	}
+	return image
+}
+
+// parseImage Parses an image the way the container runtime resolves it, e.g. `busybox` is `docker.io/library/busybox`
+func parseImage(image string) imageReference {
+	var ref imageReference
+	name := image
+	if index := strings.Index(name, "@"); index >= 0 {
+		ref.Digest = name[index+1:]
+		name = name[:index]
+	}
+	if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		ref.Tag = name[index+1:]
+		name = name[:index]
+	}
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 1 || !(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		if len(parts) == 1 {
+			name = defaultNamespace + "/" + name
+		}
+		name = defaultRegistry + "/" + name
+	}
+	ref.Repository = name
+	return ref
+}
+
+// hasPathPrefix Whether the repository is the prefix or lives underneath it
+func hasPathPrefix(repository string, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return repository == prefix || strings.HasPrefix(repository, prefix+"/")
+}
+
+// rewriteImage Rewrites the registry prefix of the image according to the longest matching rule
+func (patcher *SidecarInjectorPatcher) rewriteImage(image string) string {
+	ref := parseImage(image)
+	match := ""
+	for prefix := range patcher.ImageRegistryRewrites {
+		if hasPathPrefix(ref.Repository, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return image
+	}
+	ref.Repository = strings.TrimSuffix(patcher.ImageRegistryRewrites[match], "/") + strings.TrimPrefix(ref.Repository, strings.TrimSuffix(match, "/"))
+	return ref.String()
+}
+
+// validateImage Verifies the image against the allowed registries and the digest requirement
+func (patcher *SidecarInjectorPatcher) validateImage(image string) error {
+	ref := parseImage(image)
+	if patcher.RequireImageDigest && ref.Digest == "" {
+		return fmt.Errorf("image %s is not pinned to a digest", image)
+	}
+	if len(patcher.AllowedImageRegistries) == 0 {
+		return nil
+	}
+	for _, allowed := range patcher.AllowedImageRegistries {
+		if hasPathPrefix(ref.Repository, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("image %s is not from an allowed registry %v", image, patcher.AllowedImageRegistries)
+}
+
+// applyImagePolicy Rewrites and validates the images of every container of the sidecar
+func (patcher *SidecarInjectorPatcher) applyImagePolicy(sidecar Sidecar) (Sidecar, error) {
+	var err error
+	if sidecar.InitContainers, err = patcher.applyContainersImagePolicy(sidecar.InitContainers); err != nil {
+		return sidecar, err
+	}
+	if sidecar.Containers, err = patcher.applyContainersImagePolicy(sidecar.Containers); err != nil {
+		return sidecar, err
+	}
+	return sidecar, nil
+}
+
+func (patcher *SidecarInjectorPatcher) applyContainersImagePolicy(containers []corev1.Container) ([]corev1.Container, error) {
+	if containers == nil {
+		return nil, nil
+	}
+	rewritten := make([]corev1.Container, len(containers))
+	for index, container := range containers {
+		container.Image = patcher.rewriteImage(container.Image)
+		if err := patcher.validateImage(container.Image); err != nil {
+			return nil, fmt.Errorf("container %s: %v", container.Name, err)
+		}
+		rewritten[index] = container
+	}
+	return rewritten, nil
+}
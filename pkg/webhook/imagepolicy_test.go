@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func Test_parseImage(t *testing.T) {
+	tests := []struct {
+		image string
+		want  imageReference
+	}{
+		{image: "busybox", want: imageReference{Repository: "docker.io/library/busybox"}},
+		{image: "fluent/fluent-bit:2.1", want: imageReference{Repository: "docker.io/fluent/fluent-bit", Tag: "2.1"}},
+		{image: "localhost/agent", want: imageReference{Repository: "localhost/agent"}},
+		{image: "registry.example.com:5000/team/agent:1.0", want: imageReference{Repository: "registry.example.com:5000/team/agent", Tag: "1.0"}},
+		{image: "ghcr.io/org/agent:1.0@sha256:abc", want: imageReference{Repository: "ghcr.io/org/agent", Tag: "1.0", Digest: "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equalf(t, tt.want, parseImage(tt.image), "parseImage(%v)", tt.image)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_applyImagePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		patcher   SidecarInjectorPatcher
+		image     string
+		wantImage string
+		wantErr   assert.ErrorAssertionFunc
+	}{
+		{
+			name:      "no policy",
+			image:     "busybox",
+			wantImage: "busybox",
+			wantErr:   assert.NoError,
+		},
+		{
+			name:      "rewrite docker hub",
+			patcher:   SidecarInjectorPatcher{ImageRegistryRewrites: map[string]string{"docker.io": "mirror.example.com/dockerhub"}},
+			image:     "busybox:1.36",
+			wantImage: "mirror.example.com/dockerhub/library/busybox:1.36",
+			wantErr:   assert.NoError,
+		},
+		{
+			name: "longest rewrite wins",
+			patcher: SidecarInjectorPatcher{ImageRegistryRewrites: map[string]string{
+				"docker.io":        "mirror.example.com/dockerhub",
+				"docker.io/fluent": "mirror.example.com/fluent",
+			}},
+			image:     "fluent/fluent-bit:2.1",
+			wantImage: "mirror.example.com/fluent/fluent-bit:2.1",
+			wantErr:   assert.NoError,
+		},
+		{
+			name:      "rewrite does not match partial path",
+			patcher:   SidecarInjectorPatcher{ImageRegistryRewrites: map[string]string{"ghcr.io/org": "mirror.example.com/org"}},
+			image:     "ghcr.io/organization/agent",
+			wantImage: "ghcr.io/organization/agent",
+			wantErr:   assert.NoError,
+		},
+		{
+			name: "rewritten image is allowed",
+			patcher: SidecarInjectorPatcher{
+				ImageRegistryRewrites:  map[string]string{"docker.io": "mirror.example.com/dockerhub"},
+				AllowedImageRegistries: []string{"mirror.example.com"},
+			},
+			image:     "busybox",
+			wantImage: "mirror.example.com/dockerhub/library/busybox",
+			wantErr:   assert.NoError,
+		},
+		{
+			name:    "registry not allowed",
+			patcher: SidecarInjectorPatcher{AllowedImageRegistries: []string{"mirror.example.com"}},
+			image:   "busybox",
+			wantErr: assert.Error,
+		},
+		{
+			name:    "digest required",
+			patcher: SidecarInjectorPatcher{RequireImageDigest: true},
+			image:   "busybox:1.36",
+			wantErr: assert.Error,
+		},
+		{
+			name:      "digest pinned",
+			patcher:   SidecarInjectorPatcher{RequireImageDigest: true},
+			image:     "busybox@sha256:abc",
+			wantImage: "busybox@sha256:abc",
+			wantErr:   assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sidecar := Sidecar{InitContainers: []v1.Container{{Name: "sidecar", Image: tt.image}}}
+			got, err := tt.patcher.applyImagePolicy(sidecar)
+			if !tt.wantErr(t, err, "applyImagePolicy(%v)", sidecar) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.wantImage, got.InitContainers[0].Image, "applyImagePolicy(%v)", sidecar)
+			assert.Equalf(t, tt.image, sidecar.InitContainers[0].Image, "applyImagePolicy(%v) modified its input", sidecar)
+		})
+	}
+}
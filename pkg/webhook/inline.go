@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/pod-security-admission/api"
+)
+
+var restrictedLevelVersion = api.LevelVersion{Level: api.LevelRestricted, Version: api.LatestVersion()}
+
+// inlineSidecarsAnnotation Pod annotation defining sidecars inline
+func (patcher *SidecarInjectorPatcher) inlineSidecarsAnnotation() string {
+	return patcher.InjectPrefix + "/inline-sidecars"
+}
+
+// allowInlineSidecarsAnnotation Namespace annotation enabling inline sidecars
+func (patcher *SidecarInjectorPatcher) allowInlineSidecarsAnnotation() string {
+	return patcher.InjectPrefix + "/allow-inline-sidecars"
+}
+
+// inlineSidecars Parses the sidecars defined inline on the pod, a single sidecar or a list in YAML or JSON, if the
+// namespace allows them
+func (patcher *SidecarInjectorPatcher) inlineSidecars(namespace string, ns *corev1.Namespace, pod corev1.Pod) ([]Sidecar, error) {
+	definition, ok := pod.Annotations[patcher.inlineSidecarsAnnotation()]
+	if !ok {
+		return nil, nil
+	}
+	allowed := false
+	if ns != nil {
+		allowed, _ = strconv.ParseBool(ns.GetAnnotations()[patcher.allowInlineSidecarsAnnotation()])
+	}
+	if !allowed {
+		return nil, fmt.Errorf("inline sidecars are not allowed in namespace %s", namespace)
+	}
+	sidecars, err := parseSidecarDocuments([]byte(definition))
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling inline sidecars: %v", err)
+	}
+	for _, sidecar := range sidecars {
+		if sidecar.Extends != "" {
+			return nil, fmt.Errorf("inline sidecar %s cannot extend other sidecars", sidecar.Name)
+		}
+	}
+	return sidecars, nil
+}
+
+// inlineViolations Lists the restricted Pod Security Standards checks failed by an inline sidecar
+func inlineViolations(pod *corev1.Pod, sidecar Sidecar) []string {
+	return sidecarPodViolations(restrictedLevelVersion, pod, sidecar, nil)
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSidecarInjectorPatcher_PatchPodCreateInline(t *testing.T) {
+	allowed := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test",
+		Annotations: map[string]string{"sidecar-injector.expedia.com/allow-inline-sidecars": "true"},
+	}}
+	nonRoot, escalation := true, false
+	restricted := `{"name": "debug", "containers": [{"name": "debug", "image": "busybox", "securityContext": {
+		"runAsNonRoot": true, "allowPrivilegeEscalation": false,
+		"capabilities": {"drop": ["ALL"]}, "seccompProfile": {"type": "RuntimeDefault"}}}]}`
+	tests := []struct {
+		name      string
+		namespace *v1.Namespace
+		inline    string
+		want      []admission.PatchOperation
+		wantErr   assert.ErrorAssertionFunc
+	}{
+		{
+			name:      "restricted sidecar",
+			namespace: allowed,
+			inline:    restricted,
+			want: []admission.PatchOperation{{Op: "add", Path: "/spec/containers/-", Value: v1.Container{
+				Name:  "debug",
+				Image: "busybox",
+				SecurityContext: &v1.SecurityContext{
+					RunAsNonRoot:             &nonRoot,
+					AllowPrivilegeEscalation: &escalation,
+					Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+					SeccompProfile:           &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+				},
+			}}},
+			wantErr: assert.NoError,
+		},
+		{
+			name:      "privileged sidecar in a yaml list",
+			namespace: allowed,
+			inline: `
+                     - name: debug
+                       containers:
+                         - name: debug
+                           securityContext:
+                             privileged: true`,
+			wantErr: assert.Error,
+		},
+		{
+			name:      "not allowed in namespace",
+			namespace: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			inline:    restricted,
+			wantErr:   assert.Error,
+		},
+		{
+			name:      "extends",
+			namespace: allowed,
+			inline:    `{"name": "debug", "extends": "debug-base"}`,
+			wantErr:   assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"sidecar-injector.expedia.com/inline-sidecars": tt.inline},
+				},
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+			}
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:      fake.NewSimpleClientset(tt.namespace),
+				InjectPrefix:   "sidecar-injector.expedia.com",
+				InjectName:     "inject",
+				SidecarDataKey: "sidecars.yaml",
+			}
+			got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+			if !tt.wantErr(t, err, "PatchPodCreate()") {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LimitRangePolicyNone LimitRanges are left to the API server
+	LimitRangePolicyNone = "none"
+	// LimitRangePolicyClamp Sidecar resources are clamped between the LimitRange min and max
+	LimitRangePolicyClamp = "clamp"
+	// LimitRangePolicyMin Missing sidecar requests and limits are set to the LimitRange min, then clamped
+	LimitRangePolicyMin = "min"
+	// LimitRangePolicyDefault Missing sidecar resources are set to the LimitRange defaults, then clamped
+	LimitRangePolicyDefault = "default"
+	// LimitRangePolicyInjector Missing sidecar resources are set to the default resources of the injector, then clamped
+	LimitRangePolicyInjector = "injector"
+)
+
+// ResourceDefaults Requests and limits set on the sidecar containers missing them
+type ResourceDefaults struct {
+	Requests corev1.ResourceList `yaml:"requests"`
+	Limits   corev1.ResourceList `yaml:"limits"`
+}
+
+// String Formats the defaults for the command line
+func (defaults *ResourceDefaults) String() string {
+	if defaults == nil || (len(defaults.Requests) == 0 && len(defaults.Limits) == 0) {
+		return ""
+	}
+	value, _ := yaml.Marshal(defaults)
+	return string(value)
+}
+
+// Set Parses the defaults from the command line as YAML or JSON
+func (defaults *ResourceDefaults) Set(value string) error {
+	return yaml.Unmarshal([]byte(value), defaults)
+}
+
+// Type Describes the command line value
+func (defaults *ResourceDefaults) Type() string {
+	return "resources"
+}
+
+// namespaceContainerLimits Lists the container limits of the LimitRanges of the namespace
+func (patcher *SidecarInjectorPatcher) namespaceContainerLimits(ctx context.Context, namespace string) []corev1.LimitRangeItem {
+	if patcher.LimitRangePolicy == "" || patcher.LimitRangePolicy == LimitRangePolicyNone {
+		return nil
+	}
+	limitRanges, err := patcher.K8sClient.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		admission.Logger(ctx).Errorf("error listing limitranges of namespace %s, skipping limitrange policy - %v", namespace, err)
+		return nil
+	}
+	var limits []corev1.LimitRangeItem
+	for _, limitRange := range limitRanges.Items {
+		for _, limit := range limitRange.Spec.Limits {
+			if limit.Type == corev1.LimitTypeContainer {
+				limits = append(limits, limit)
+			}
+		}
+	}
+	return limits
+}
+
+// fillResources Sets the resources missing from the container resources
+func fillResources(resources corev1.ResourceList, defaults corev1.ResourceList) corev1.ResourceList {
+	for name, quantity := range defaults {
+		if _, ok := resources[name]; !ok {
+			resources = setResource(resources, name, quantity.DeepCopy())
+		}
+	}
+	return resources
+}
+
+// fillLimits Sets the limits missing from the container resources, never below the request of the resource
+func fillLimits(limits corev1.ResourceList, defaults corev1.ResourceList, requests corev1.ResourceList) corev1.ResourceList {
+	for name, quantity := range defaults {
+		if _, ok := limits[name]; ok {
+			continue
+		}
+		if request, ok := requests[name]; ok && request.Cmp(quantity) > 0 {
+			quantity = request
+		}
+		limits = setResource(limits, name, quantity.DeepCopy())
+	}
+	return limits
+}
+
+// clampResources Keeps the container resources between the LimitRange min and max
+func clampResources(resources corev1.ResourceList, limit corev1.LimitRangeItem) corev1.ResourceList {
+	policy := SidecarResourcePolicy{Min: limit.Min, Max: limit.Max}
+	for name, quantity := range resources {
+		resources[name] = policy.clampQuantity(name, quantity)
+	}
+	return resources
+}
+
+// applyLimitRanges Fills in and clamps the resources of the sidecar containers according to the LimitRanges
+func (patcher *SidecarInjectorPatcher) applyLimitRanges(limits []corev1.LimitRangeItem, sidecar Sidecar) Sidecar {
+	if len(limits) == 0 && patcher.LimitRangePolicy != LimitRangePolicyInjector {
+		return sidecar
+	}
+	sidecar.InitContainers = patcher.applyContainersLimitRanges(limits, sidecar.InitContainers)
+	sidecar.Containers = patcher.applyContainersLimitRanges(limits, sidecar.Containers)
+	return sidecar
+}
+
+func (patcher *SidecarInjectorPatcher) applyContainersLimitRanges(limits []corev1.LimitRangeItem, containers []corev1.Container) []corev1.Container {
+	if containers == nil {
+		return nil
+	}
+	limited := make([]corev1.Container, len(containers))
+	for index, container := range containers {
+		resources := container.Resources.DeepCopy()
+		if patcher.LimitRangePolicy == LimitRangePolicyInjector {
+			resources.Requests = fillResources(resources.Requests, patcher.DefaultResources.Requests)
+			resources.Limits = fillLimits(resources.Limits, patcher.DefaultResources.Limits, resources.Requests)
+		}
+		for _, limit := range limits {
+			switch patcher.LimitRangePolicy {
+			case LimitRangePolicyMin:
+				resources.Requests = fillResources(resources.Requests, limit.Min)
+				resources.Limits = fillLimits(resources.Limits, limit.Min, resources.Requests)
+			case LimitRangePolicyDefault:
+				resources.Requests = fillResources(resources.Requests, limit.DefaultRequest)
+				resources.Limits = fillResources(resources.Limits, limit.Default)
+			}
+			resources.Requests = clampResources(resources.Requests, limit)
+			resources.Limits = clampResources(resources.Limits, limit)
+		}
+		// requests may not exceed limits after clamping
+		for name, request := range resources.Requests {
+			if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+				resources.Requests[name] = limit.DeepCopy()
+			}
+		}
+		container.Resources = *resources
+		limited[index] = container
+	}
+	return limited
+}
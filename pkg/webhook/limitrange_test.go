@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSidecarInjectorPatcher_applyLimitRanges(t *testing.T) {
+	limitRange := &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "test"},
+		Spec: v1.LimitRangeSpec{Limits: []v1.LimitRangeItem{
+			{
+				Type: v1.LimitTypeContainer,
+				Min:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("20m")},
+				Max:  v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+				DefaultRequest: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1"),
+					v1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+				Default: v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+			},
+			{
+				Type: v1.LimitTypePod,
+				Max:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("1m")},
+			},
+		}},
+	}
+	container := v1.Container{Name: "agent", Resources: v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("8Gi")},
+	}}
+	tests := []struct {
+		name         string
+		policy       string
+		wantRequests v1.ResourceList
+		wantLimits   v1.ResourceList
+	}{
+		{
+			name:       "policy none",
+			policy:     LimitRangePolicyNone,
+			wantLimits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("8Gi")},
+		},
+		{
+			name:       "clamp to max",
+			policy:     LimitRangePolicyClamp,
+			wantLimits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+		{
+			name:         "fill from min",
+			policy:       LimitRangePolicyMin,
+			wantRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("20m")},
+			wantLimits: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("20m"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		{
+			name:   "fill from injector defaults",
+			policy: LimitRangePolicyInjector,
+			wantRequests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("50m"),
+				v1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+			wantLimits: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("100m"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		{
+			name:   "fill from defaults",
+			policy: LimitRangePolicyDefault,
+			wantRequests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+			wantLimits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:        fake.NewSimpleClientset(limitRange),
+				LimitRangePolicy: tt.policy,
+				DefaultResources: ResourceDefaults{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("50m"), v1.ResourceMemory: resource.MustParse("64Mi")},
+					Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("128Mi")},
+				},
+			}
+			sidecar := Sidecar{Containers: []v1.Container{container}}
+			got := patcher.applyLimitRanges(patcher.namespaceContainerLimits(context.Background(), "test"), sidecar)
+			resources := got.Containers[0].Resources
+			assert.Equal(t, len(tt.wantRequests), len(resources.Requests))
+			for name, want := range tt.wantRequests {
+				assert.Zerof(t, want.Cmp(resources.Requests[name]), "requests.%s", name)
+			}
+			assert.Equal(t, len(tt.wantLimits), len(resources.Limits))
+			for name, want := range tt.wantLimits {
+				assert.Zerof(t, want.Cmp(resources.Limits[name]), "limits.%s", name)
+			}
+			assert.Equal(t, resource.MustParse("8Gi"), container.Resources.Limits[v1.ResourceMemory], "input modified")
+		})
+	}
+}
+
+func Test_fillLimits(t *testing.T) {
+	defaults := v1.ResourceList{v1.ResourceCPU: resource.MustParse("20m"), v1.ResourceMemory: resource.MustParse("64Mi")}
+	requests := v1.ResourceList{v1.ResourceCPU: resource.MustParse("200m")}
+	limits := v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")}
+	got := fillLimits(limits, defaults, requests)
+	assert.Equal(t, "200m", got.Cpu().String(), "limit below the request")
+	assert.Equal(t, "256Mi", got.Memory().String(), "existing limit")
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "sidecar_injector"
+
+const (
+	// MetricsLabelNamespace Label of the namespace of the pods and ConfigMaps
+	MetricsLabelNamespace = "namespace"
+	// MetricsLabelSidecar Label of the sidecar names, which are also the names of their ConfigMaps
+	MetricsLabelSidecar = "sidecar"
+)
+
+const (
+	configmapLookupFound      = "found"
+	configmapLookupNotFound   = "not-found"
+	configmapLookupError      = "error"
+	configmapLookupParseError = "parse-error"
+)
+
+var rolloutInjections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "rollout_injections_total",
+	Help:      "Number of injections of sidecars under rollout by revision.",
+}, []string{"sidecar", "revision"})
+
+var deprecatedInjections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "deprecated_injections_total",
+	Help:      "Number of injections referencing deprecated sidecars.",
+}, []string{"sidecar"})
+
+var sidecarInjections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "injections_total",
+	Help:      "Number of sidecars injected into pods.",
+}, []string{"namespace", "sidecar"})
+
+var configmapLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "configmap_lookups_total",
+	Help:      "Number of lookups of sidecar ConfigMaps by outcome: found, not-found, error or parse-error.",
+}, []string{"namespace", "sidecar", "outcome"})
+
+var sidecarDefinitions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "sidecar_definitions",
+	Help:      "Number of sidecar definitions read from the ConfigMaps at their last lookup.",
+}, []string{"namespace", "sidecar"})
+
+// definitionCounts Number of sidecar definitions of each ConfigMap, summed up into the gauge by the retained labels
+var definitionCounts = struct {
+	sync.Mutex
+	counts map[[2]string]int
+}{counts: map[[2]string]int{}}
+
+// metricLabel Value of the label, empty when the label is not retained to limit the cardinality of the metrics
+func (patcher *SidecarInjectorPatcher) metricLabel(label string, value string) string {
+	if !slices.Contains(patcher.MetricsLabels, label) {
+		return ""
+	}
+	return value
+}
+
+// recordConfigMapLookup Counts the outcome of a ConfigMap lookup and updates the number of definitions it holds
+func (patcher *SidecarInjectorPatcher) recordConfigMapLookup(namespace string, configmap string, outcome string, definitions int) {
+	configmapLookups.WithLabelValues(patcher.metricLabel(MetricsLabelNamespace, namespace), patcher.metricLabel(MetricsLabelSidecar, configmap), outcome).Inc()
+	if outcome == configmapLookupError {
+		return
+	}
+
+	definitionCounts.Lock()
+	defer definitionCounts.Unlock()
+	if definitions == 0 {
+		delete(definitionCounts.counts, [2]string{namespace, configmap})
+	} else {
+		definitionCounts.counts[[2]string{namespace, configmap}] = definitions
+	}
+	sums := map[[2]string]int{}
+	for key, count := range definitionCounts.counts {
+		sums[[2]string{patcher.metricLabel(MetricsLabelNamespace, key[0]), patcher.metricLabel(MetricsLabelSidecar, key[1])}] += count
+	}
+	sidecarDefinitions.Reset()
+	for labels, sum := range sums {
+		sidecarDefinitions.WithLabelValues(labels[0], labels[1]).Set(float64(sum))
+	}
+}
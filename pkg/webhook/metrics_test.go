@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSidecarInjectorPatcher_recordConfigMapLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels []string
+		want   map[[2]string]float64
+	}{
+		{
+			name:   "all labels",
+			labels: []string{MetricsLabelNamespace, MetricsLabelSidecar},
+			want:   map[[2]string]float64{{"test", "envoy"}: 2, {"test", "otel"}: 1, {"other", "envoy"}: 3},
+		},
+		{
+			name:   "sidecar label",
+			labels: []string{MetricsLabelSidecar},
+			want:   map[[2]string]float64{{"", "envoy"}: 5, {"", "otel"}: 1},
+		},
+		{
+			name: "no labels",
+			want: map[[2]string]float64{{"", ""}: 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definitionCounts.counts = map[[2]string]int{}
+			patcher := &SidecarInjectorPatcher{MetricsLabels: tt.labels}
+			patcher.recordConfigMapLookup("test", "envoy", configmapLookupFound, 2)
+			patcher.recordConfigMapLookup("test", "otel", configmapLookupFound, 1)
+			patcher.recordConfigMapLookup("test", "removed", configmapLookupFound, 4)
+			patcher.recordConfigMapLookup("test", "removed", configmapLookupNotFound, 0)
+			patcher.recordConfigMapLookup("other", "envoy", configmapLookupFound, 3)
+			patcher.recordConfigMapLookup("other", "envoy", configmapLookupError, 0)
+
+			assert.Equal(t, len(tt.want), testutil.CollectAndCount(sidecarDefinitions))
+			for labels, want := range tt.want {
+				assert.Equalf(t, want, testutil.ToFloat64(sidecarDefinitions.WithLabelValues(labels[0], labels[1])), "sidecar_definitions%v", labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	parameterTypeString = "string"
+	parameterTypeInt    = "int"
+	parameterTypeNumber = "number"
+	parameterTypeBool   = "bool"
+)
+
+// referenceNamePattern Sidecar reference starting with a ConfigMap name, optionally followed by a version or parameters
+var referenceNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?\s*([@(]|$)`)
+
+// SidecarParameter Parameter of a sidecar set per pod, exposed as `.Params` to the rendering of the sidecar
+type SidecarParameter struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Default  string `yaml:"default"`
+	Required bool   `yaml:"required"`
+}
+
+// splitReferences Splits the inject annotation on the commas outside of parameter lists. A part that does not start
+// with a sidecar name continues the version range of the preceding reference, e.g. `logs@>=1.0, <2.0`.
+func splitReferences(value string) []string {
+	var references []string
+	depth, start := 0, 0
+	split := func(end int) {
+		part := strings.TrimSpace(value[start:end])
+		if last := len(references) - 1; last >= 0 && !referenceNamePattern.MatchString(part) &&
+			strings.Contains(references[last], "@") && !strings.Contains(references[last], "(") {
+			references[last] = references[last] + ", " + part
+			return
+		}
+		references = append(references, part)
+	}
+	for index, char := range value {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				split(index)
+				start = index + 1
+			}
+		}
+	}
+	split(len(value))
+	return references
+}
+
+// parseParameters Parses a `key=value,...` parameter list
+func parseParameters(list string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, param := range strings.Split(list, ",") {
+		if strings.TrimSpace(param) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(param, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("parameter %q is not of the form key=value", strings.TrimSpace(param))
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return params, nil
+}
+
+// validateParameter Checks the value matches the type of the parameter
+func validateParameter(parameter SidecarParameter, value string) error {
+	var err error
+	switch parameter.Type {
+	case "", parameterTypeString:
+	case parameterTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case parameterTypeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case parameterTypeBool:
+		_, err = strconv.ParseBool(value)
+	default:
+		return fmt.Errorf("parameter %s has an unknown type %s", parameter.Name, parameter.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("parameter %s value %q is not a valid %s", parameter.Name, value, parameter.Type)
+	}
+	return nil
+}
+
+// undeclaredParameter Returns a parameter of the reference that none of its sidecars declares, reference parameters
+// being shared by all the sidecars of a ConfigMap
+func undeclaredParameter(reference sidecarReference, sidecars []Sidecar) (string, bool) {
+	declared := map[string]bool{}
+	for _, sidecar := range sidecars {
+		for _, parameter := range sidecar.Parameters {
+			declared[parameter.Name] = true
+		}
+	}
+	names := lo.Keys(reference.Params)
+	slices.Sort(names)
+	for _, name := range names {
+		if !declared[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// sidecarParameters Values of the sidecar parameters from the inject annotation, the `<sidecar>.<param>` pod
+// annotations or the defaults, ignoring the reference parameters the sidecar does not declare
+func (patcher *SidecarInjectorPatcher) sidecarParameters(pod corev1.Pod, sidecar Sidecar) (map[string]string, error) {
+	params := map[string]string{}
+	for _, parameter := range sidecar.Parameters {
+		value, ok := sidecar.params[parameter.Name]
+		if !ok {
+			value, ok = pod.Annotations[patcher.sidecarAnnotation(sidecar, parameter.Name)]
+		}
+		if !ok {
+			if parameter.Required {
+				return nil, fmt.Errorf("missing required parameter %s", parameter.Name)
+			}
+			if parameter.Default == "" {
+				// an optional parameter without a default renders empty whatever its type
+				params[parameter.Name] = ""
+				continue
+			}
+			value = parameter.Default
+		}
+		if err := validateParameter(parameter, value); err != nil {
+			return nil, err
+		}
+		params[parameter.Name] = value
+	}
+	return params, nil
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_splitReferences(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "otel(samplingRate=0.1,port=9000), logs@^1.0,vault", want: []string{"otel(samplingRate=0.1,port=9000)", "logs@^1.0", "vault"}},
+		{value: "logs@>=1.0, <2.0, vault", want: []string{"logs@>=1.0, <2.0", "vault"}},
+		{value: "logs@>=1.0,<2.0 || >=3.0,otel(port=9000)", want: []string{"logs@>=1.0, <2.0 || >=3.0", "otel(port=9000)"}},
+		{value: "logs@>=1.0, vault@ <2.0", want: []string{"logs@>=1.0", "vault@ <2.0"}},
+		{value: "otel(port=9000), <2.0", want: []string{"otel(port=9000)", "<2.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equalf(t, tt.want, splitReferences(tt.value), "splitReferences(%v)", tt.value)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_sidecarParameters(t *testing.T) {
+	parameters := []SidecarParameter{
+		{Name: "samplingRate", Type: parameterTypeNumber, Default: "1.0"},
+		{Name: "port", Type: parameterTypeInt, Required: true},
+		{Name: "debug", Type: parameterTypeBool, Default: "false"},
+		{Name: "adminPort", Type: parameterTypeInt},
+	}
+	tests := []struct {
+		name        string
+		params      map[string]string
+		annotations map[string]string
+		want        map[string]string
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "reference parameters and defaults",
+			params:  map[string]string{"port": "9000"},
+			want:    map[string]string{"samplingRate": "1.0", "port": "9000", "debug": "false", "adminPort": ""},
+			wantErr: assert.NoError,
+		},
+		{
+			name:        "annotation parameters",
+			params:      map[string]string{"port": "9000"},
+			annotations: map[string]string{"sidecar-injector.expedia.com/otel.port": "9001", "sidecar-injector.expedia.com/otel.debug": "true"},
+			want:        map[string]string{"samplingRate": "1.0", "port": "9000", "debug": "true", "adminPort": ""},
+			wantErr:     assert.NoError,
+		},
+		{
+			name:    "missing required parameter",
+			wantErr: assert.Error,
+		},
+		{
+			name:    "parameter of another sidecar",
+			params:  map[string]string{"port": "9000", "level": "info"},
+			want:    map[string]string{"samplingRate": "1.0", "port": "9000", "debug": "false", "adminPort": ""},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "invalid type",
+			params:  map[string]string{"port": "http"},
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{InjectPrefix: "sidecar-injector.expedia.com"}
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			got, err := patcher.sidecarParameters(pod, Sidecar{Name: "otel", Parameters: parameters, params: tt.params})
+			if !tt.wantErr(t, err, "sidecarParameters(%v)", tt.params) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "sidecarParameters(%v)", tt.params)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateParameters(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "otel-agent", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: otel
+                       parameters:
+                         - name: samplingRate
+                           type: number
+                           default: "1.0"
+                         - name: logLevel
+                           default: info
+                       containers:
+                         - name: otel
+                           args: [ "--sampling-rate={{ .Params.samplingRate }}", "--log-level={{ .Params.logLevel }}" ]`,
+		},
+	}
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"sidecar-injector.expedia.com/inject":        "otel-agent(samplingRate=0.1)",
+				"sidecar-injector.expedia.com/otel.logLevel": "debug",
+			},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+	}
+	patcher := &SidecarInjectorPatcher{
+		K8sClient:      fake.NewSimpleClientset(configmap),
+		InjectPrefix:   "sidecar-injector.expedia.com",
+		InjectName:     "inject",
+		SidecarDataKey: "sidecars.yaml",
+	}
+	got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+	assert.NoError(t, err)
+	assert.Equal(t, []admission.PatchOperation{
+		{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "otel", Args: []string{"--sampling-rate=0.1", "--log-level=debug"}}},
+	}, got)
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateSharedParameters(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "observability", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: otel
+                       parameters:
+                         - name: samplingRate
+                           type: number
+                       containers:
+                         - name: otel
+                           args: [ "--sampling-rate={{ .Params.samplingRate }}" ]
+                     - name: fluent-bit
+                       containers:
+                         - name: fluent-bit`,
+		},
+	}
+	tests := []struct {
+		name    string
+		inject  string
+		want    []admission.PatchOperation
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "parameter declared by one sidecar",
+			inject: "observability(samplingRate=0.1)",
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "otel", Args: []string{"--sampling-rate=0.1"}}},
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "fluent-bit"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "optional parameter left out",
+			inject: "observability",
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "otel", Args: []string{"--sampling-rate="}}},
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "fluent-bit"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "parameter declared by no sidecar",
+			inject:  "observability(level=debug)",
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"sidecar-injector.expedia.com/inject": tt.inject}},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+			}
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:      fake.NewSimpleClientset(configmap),
+				InjectPrefix:   "sidecar-injector.expedia.com",
+				InjectName:     "inject",
+				SidecarDataKey: "sidecars.yaml",
+			}
+			got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+			if !tt.wantErr(t, err, "PatchPodCreate()") || err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
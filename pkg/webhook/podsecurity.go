@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/pod-security-admission/api"
+	"k8s.io/pod-security-admission/policy"
+)
+
+const (
+	// PodSecurityActionNone Pod Security Standards are not checked
+	PodSecurityActionNone = "none"
+	// PodSecurityActionDeny Pods are denied when a sidecar violates the namespace Pod Security Standards
+	PodSecurityActionDeny = "deny"
+	// PodSecurityActionSkip Sidecars violating the namespace Pod Security Standards are not injected
+	PodSecurityActionSkip = "skip"
+)
+
+var privilegedLevelVersion = api.LevelVersion{Level: api.LevelPrivileged, Version: api.LatestVersion()}
+
+var podSecurityEvaluator = newPodSecurityEvaluator()
+
+func newPodSecurityEvaluator() policy.Evaluator {
+	evaluator, err := policy.NewEvaluator(policy.DefaultChecks())
+	if err != nil {
+		panic(fmt.Sprintf("invalid pod security checks: %v", err))
+	}
+	return evaluator
+}
+
+// namespacePodSecurity Resolves the Pod Security Standards level enforced on the namespace
+func (patcher *SidecarInjectorPatcher) namespacePodSecurity(ctx context.Context, ns *corev1.Namespace) api.LevelVersion {
+	if patcher.PodSecurityAction == "" || patcher.PodSecurityAction == PodSecurityActionNone || ns == nil {
+		return privilegedLevelVersion
+	}
+	podSecurity, errs := api.PolicyToEvaluate(ns.GetLabels(), api.Policy{Enforce: privilegedLevelVersion})
+	if len(errs) > 0 {
+		admission.Logger(ctx).Warnf("invalid pod security labels on namespace %s: %v", ns.GetName(), errs.ToAggregate())
+	}
+	return podSecurity.Enforce
+}
+
+// podSecurityViolations Lists the Pod Security Standards checks that the sidecar makes the pod fail
+func podSecurityViolations(levelVersion api.LevelVersion, pod *corev1.Pod, sidecar Sidecar) []string {
+	if levelVersion.Level == api.LevelPrivileged {
+		return nil
+	}
+	existing := map[string]bool{}
+	for _, result := range podSecurityEvaluator.EvaluatePod(levelVersion, &pod.ObjectMeta, &pod.Spec) {
+		if !result.Allowed {
+			existing[result.ForbiddenReason+result.ForbiddenDetail] = true
+		}
+	}
+	return sidecarPodViolations(levelVersion, pod, sidecar, existing)
+}
+
+// sidecarPodViolations Lists the Pod Security Standards checks failed by the sidecar alone, except the excluded ones
+func sidecarPodViolations(levelVersion api.LevelVersion, pod *corev1.Pod, sidecar Sidecar, excluded map[string]bool) []string {
+	// evaluate the sidecar alone on the pod level spec so that details only name sidecar fields
+	sidecarPod := pod.DeepCopy()
+	sidecarPod.Spec.InitContainers = sidecar.InitContainers
+	sidecarPod.Spec.Containers = sidecar.Containers
+	sidecarPod.Spec.Volumes = sidecar.Volumes
+
+	var violations []string
+	for _, result := range podSecurityEvaluator.EvaluatePod(levelVersion, &sidecarPod.ObjectMeta, &sidecarPod.Spec) {
+		if result.Allowed || excluded[result.ForbiddenReason+result.ForbiddenDetail] {
+			continue
+		}
+		violation := result.ForbiddenReason
+		if result.ForbiddenDetail != "" {
+			violation = fmt.Sprintf("%s (%s)", result.ForbiddenReason, result.ForbiddenDetail)
+		}
+		violations = append(violations, violation)
+	}
+	return violations
+}
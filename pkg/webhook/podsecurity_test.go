@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/pod-security-admission/api"
+)
+
+func TestSidecarInjectorPatcher_namespacePodSecurity(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		namespace *v1.Namespace
+		want      api.Level
+	}{
+		{
+			name:   "checks disabled",
+			action: PodSecurityActionNone,
+			namespace: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "test",
+				Labels: map[string]string{"pod-security.kubernetes.io/enforce": "restricted"},
+			}},
+			want: api.LevelPrivileged,
+		},
+		{
+			name:   "namespace not found",
+			action: PodSecurityActionDeny,
+			want:   api.LevelPrivileged,
+		},
+		{
+			name:      "namespace without enforce label",
+			action:    PodSecurityActionDeny,
+			namespace: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			want:      api.LevelPrivileged,
+		},
+		{
+			name:   "namespace enforcing restricted",
+			action: PodSecurityActionSkip,
+			namespace: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "test",
+				Labels: map[string]string{"pod-security.kubernetes.io/enforce": "restricted"},
+			}},
+			want: api.LevelRestricted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{PodSecurityAction: tt.action}
+			got := patcher.namespacePodSecurity(context.Background(), tt.namespace)
+			assert.Equalf(t, tt.want, got.Level, "namespacePodSecurity(%v)", tt.namespace)
+		})
+	}
+}
+
+func Test_podSecurityViolations(t *testing.T) {
+	privileged := true
+	baseline := api.LevelVersion{Level: api.LevelBaseline, Version: api.LatestVersion()}
+	tests := []struct {
+		name         string
+		levelVersion api.LevelVersion
+		pod          v1.Pod
+		sidecar      Sidecar
+		want         []string
+	}{
+		{
+			name:         "privileged namespace allows anything",
+			levelVersion: privilegedLevelVersion,
+			sidecar: Sidecar{Containers: []v1.Container{{
+				Name:            "sidecar",
+				SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+			}}},
+			want: nil,
+		},
+		{
+			name:         "compliant sidecar",
+			levelVersion: baseline,
+			sidecar:      Sidecar{Containers: []v1.Container{{Name: "sidecar"}}},
+			want:         nil,
+		},
+		{
+			name:         "privileged sidecar",
+			levelVersion: baseline,
+			pod:          v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}},
+			sidecar: Sidecar{Containers: []v1.Container{{
+				Name:            "sidecar",
+				SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+			}}},
+			want: []string{`privileged (container "sidecar" must not set securityContext.privileged=true)`},
+		},
+		{
+			name:         "violations of the app are not blamed on the sidecar",
+			levelVersion: baseline,
+			pod: v1.Pod{Spec: v1.PodSpec{
+				HostNetwork: true,
+				Containers:  []v1.Container{{Name: "app"}},
+			}},
+			sidecar: Sidecar{Containers: []v1.Container{{Name: "sidecar"}}},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, podSecurityViolations(tt.levelVersion, &tt.pod, tt.sidecar), "podSecurityViolations(%v, %v, %v)", tt.levelVersion, tt.pod, tt.sidecar)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreatePodSecurity(t *testing.T) {
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "test",
+		Labels: map[string]string{"pod-security.kubernetes.io/enforce": "baseline"},
+	}}
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-sidecar", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: privileged
+                       containers:
+                         - name: privileged
+                           securityContext:
+                             privileged: true`,
+		},
+	}
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "my-sidecar"},
+	}}
+	tests := []struct {
+		name         string
+		action       string
+		wantLen      int
+		wantWarnings []string
+		wantErr      assert.ErrorAssertionFunc
+	}{
+		{name: "not checked", action: PodSecurityActionNone, wantLen: 1, wantErr: assert.NoError},
+		{
+			name:    "skipped",
+			action:  PodSecurityActionSkip,
+			wantLen: 0,
+			wantWarnings: []string{
+				`skipping sidecar privileged from configmap test/my-sidecar violates PodSecurity "baseline:latest": privileged (container "privileged" must not set securityContext.privileged=true)`,
+			},
+			wantErr: assert.NoError,
+		},
+		{name: "denied", action: PodSecurityActionDeny, wantLen: 0, wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:         fake.NewSimpleClientset(namespace, configmap),
+				InjectPrefix:      "sidecar-injector.expedia.com",
+				InjectName:        "inject",
+				SidecarDataKey:    "sidecars.yaml",
+				PodSecurityAction: tt.action,
+			}
+			ctx := admission.WithWarnings(context.Background())
+			got, err := patcher.PatchPodCreate(ctx, "test", pod)
+			if !tt.wantErr(t, err, "PatchPodCreate()") {
+				return
+			}
+			assert.Len(t, got, tt.wantLen)
+			assert.Equal(t, tt.wantWarnings, admission.Warnings(ctx))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PortConflictActionDeny Pods with conflicting sidecar ports are denied
+	PortConflictActionDeny = "deny"
+	// PortConflictActionWarn Pods with conflicting sidecar ports are admitted with a warning
+	PortConflictActionWarn = "warn"
+)
+
+// SidecarAutoPort A named port of a sidecar container assigned from the auto port range
+type SidecarAutoPort struct {
+	Name string `yaml:"name"`
+	Env  string `yaml:"env"`
+}
+
+// PortRange Range of ports automatically assigned to sidecars
+type PortRange struct {
+	Min int32
+	Max int32
+}
+
+// String Formats the range for the command line
+func (portRange *PortRange) String() string {
+	if portRange == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", portRange.Min, portRange.Max)
+}
+
+// Set Parses the range from the command line, e.g. 15000-15999
+func (portRange *PortRange) Set(value string) error {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return fmt.Errorf("port range %q is not formatted as min-max", value)
+	}
+	min, err := strconv.ParseInt(strings.TrimSpace(from), 10, 32)
+	if err != nil {
+		return err
+	}
+	max, err := strconv.ParseInt(strings.TrimSpace(to), 10, 32)
+	if err != nil {
+		return err
+	}
+	if min < 1 || max > 65535 || min > max {
+		return fmt.Errorf("port range %q is invalid", value)
+	}
+	portRange.Min, portRange.Max = int32(min), int32(max)
+	return nil
+}
+
+// Type Describes the command line value
+func (portRange *PortRange) Type() string {
+	return "range"
+}
+
+// autoPortEnv Environment variable exposing the port, e.g. ENVOY_ADMIN_PORT
+func autoPortEnv(sidecar Sidecar, port SidecarAutoPort) string {
+	if port.Env != "" {
+		return port.Env
+	}
+	name := strings.ToUpper(sidecar.Name + "_" + port.Name + "_PORT")
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// usedPorts Collects the container and host ports already used in the pod
+func usedPorts(containers []corev1.Container) map[int32]bool {
+	used := map[int32]bool{}
+	for _, container := range containers {
+		for _, port := range container.Ports {
+			used[port.ContainerPort] = true
+			if port.HostPort != 0 {
+				used[port.HostPort] = true
+			}
+		}
+	}
+	return used
+}
+
+// assignAutoPorts Assigns free ports to the auto ports of the sidecar, returning the environment variables exposing them
+func (patcher *SidecarInjectorPatcher) assignAutoPorts(pod *corev1.Pod, sidecar Sidecar) (Sidecar, []corev1.EnvVar, error) {
+	if len(sidecar.AutoPorts) == 0 {
+		return sidecar, nil, nil
+	}
+	if patcher.AutoPortRange.Max == 0 {
+		return sidecar, nil, fmt.Errorf("auto ports require an auto port range to be configured")
+	}
+	used := usedPorts(append(append([]corev1.Container{}, pod.Spec.Containers...), sidecar.Containers...))
+	containers := make([]corev1.Container, len(sidecar.Containers))
+	for index, container := range sidecar.Containers {
+		container.Ports = append([]corev1.ContainerPort{}, container.Ports...)
+		containers[index] = container
+	}
+	var env []corev1.EnvVar
+	for _, autoPort := range sidecar.AutoPorts {
+		assigned := false
+		for index := range containers {
+			for portIndex := range containers[index].Ports {
+				port := &containers[index].Ports[portIndex]
+				if port.Name != autoPort.Name {
+					continue
+				}
+				free, err := patcher.freePort(used)
+				if err != nil {
+					return sidecar, nil, err
+				}
+				port.ContainerPort = free
+				used[free] = true
+				env = append(env, corev1.EnvVar{Name: autoPortEnv(sidecar, autoPort), Value: strconv.Itoa(int(free))})
+				assigned = true
+			}
+		}
+		if !assigned {
+			return sidecar, nil, fmt.Errorf("auto port %s is not a port of any sidecar container", autoPort.Name)
+		}
+	}
+	for index := range containers {
+		containers[index].Env = append(append([]corev1.EnvVar{}, containers[index].Env...), env...)
+	}
+	sidecar.Containers = containers
+	return sidecar, env, nil
+}
+
+func (patcher *SidecarInjectorPatcher) freePort(used map[int32]bool) (int32, error) {
+	for port := patcher.AutoPortRange.Min; port <= patcher.AutoPortRange.Max; port++ {
+		if !used[port] {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port left in range %s", patcher.AutoPortRange.String())
+}
+
+func portProtocol(port corev1.ContainerPort) corev1.Protocol {
+	if port.Protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return port.Protocol
+}
+
+// portConflicts Lists the container and host ports of the sidecar that are already used in the pod
+func portConflicts(pod *corev1.Pod, sidecar Sidecar) []string {
+	var conflicts []string
+	for _, container := range sidecar.Containers {
+		for _, port := range container.Ports {
+			for _, existing := range pod.Spec.Containers {
+				if sidecar.replaces[existing.Name] {
+					continue
+				}
+				for _, existingPort := range existing.Ports {
+					if portProtocol(port) != portProtocol(existingPort) {
+						continue
+					}
+					if port.ContainerPort == existingPort.ContainerPort {
+						conflicts = append(conflicts, fmt.Sprintf("container port %d/%s of container %s is used by container %s", port.ContainerPort, portProtocol(port), container.Name, existing.Name))
+					}
+					if port.HostPort != 0 && port.HostPort == existingPort.HostPort && (port.HostIP == "" || existingPort.HostIP == "" || port.HostIP == existingPort.HostIP) {
+						conflicts = append(conflicts, fmt.Sprintf("host port %d/%s of container %s is used by container %s", port.HostPort, portProtocol(port), container.Name, existing.Name))
+					}
+				}
+			}
+		}
+	}
+	return conflicts
+}
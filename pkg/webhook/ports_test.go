@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPortRange_Set(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PortRange
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{value: "15000-15999", want: PortRange{Min: 15000, Max: 15999}, wantErr: assert.NoError},
+		{value: "15000", wantErr: assert.Error},
+		{value: "16000-15000", wantErr: assert.Error},
+		{value: "0-100", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var got PortRange
+			if !tt.wantErr(t, got.Set(tt.value), "Set(%v)", tt.value) {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "Set(%v)", tt.value)
+		})
+	}
+}
+
+func Test_portConflicts(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+		Name: "app",
+		Ports: []v1.ContainerPort{
+			{ContainerPort: 8080},
+			{ContainerPort: 53, Protocol: v1.ProtocolUDP},
+			{ContainerPort: 9090, HostPort: 9090},
+		},
+	}}}}
+	tests := []struct {
+		name string
+		port v1.ContainerPort
+		want []string
+	}{
+		{name: "free port", port: v1.ContainerPort{ContainerPort: 15000}, want: nil},
+		{name: "container port", port: v1.ContainerPort{ContainerPort: 8080, Protocol: v1.ProtocolTCP}, want: []string{"container port 8080/TCP of container sidecar is used by container app"}},
+		{name: "other protocol", port: v1.ContainerPort{ContainerPort: 53}, want: nil},
+		{name: "host port", port: v1.ContainerPort{ContainerPort: 15000, HostPort: 9090}, want: []string{"host port 9090/TCP of container sidecar is used by container app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sidecar := Sidecar{Containers: []v1.Container{{Name: "sidecar", Ports: []v1.ContainerPort{tt.port}}}}
+			assert.Equalf(t, tt.want, portConflicts(pod, sidecar), "portConflicts(%v)", tt.port)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreatePorts(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "envoy", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: envoy
+                       autoPorts:
+                         - name: admin
+                       containers:
+                         - name: envoy
+                           ports:
+                             - name: admin
+                             - name: http
+                               containerPort: 8080`,
+		},
+	}
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "envoy"},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:  "app",
+			Ports: []v1.ContainerPort{{ContainerPort: 8080}, {ContainerPort: 15000}},
+		}}},
+	}
+	tests := []struct {
+		name    string
+		action  string
+		want    []admission.PatchOperation
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "conflict warned about and auto port assigned",
+			action: PortConflictActionWarn,
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{
+					Name: "envoy",
+					Ports: []v1.ContainerPort{
+						{Name: "admin", ContainerPort: 15001},
+						{Name: "http", ContainerPort: 8080},
+					},
+					Env: []v1.EnvVar{{Name: "ENVOY_ADMIN_PORT", Value: "15001"}},
+				}},
+				{Op: "add", Path: "/spec/containers/0/env", Value: []v1.EnvVar{{Name: "ENVOY_ADMIN_PORT", Value: "15001"}}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "conflict denied",
+			action:  PortConflictActionDeny,
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:          fake.NewSimpleClientset(configmap),
+				InjectPrefix:       "sidecar-injector.expedia.com",
+				InjectName:         "inject",
+				SidecarDataKey:     "sidecars.yaml",
+				PortConflictAction: tt.action,
+				AutoPortRange:      PortRange{Min: 15000, Max: 15999},
+			}
+			got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+			if !tt.wantErr(t, err, "PatchPodCreate()") {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
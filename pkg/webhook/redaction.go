@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+)
+
+const redactedValue = "[REDACTED]"
+
+// secretReferenceKeys Fields referencing secrets, from env valueFrom, envFrom, volumes and projected volumes
+var secretReferenceKeys = map[string]bool{"secretKeyRef": true, "secretRef": true, "secret": true}
+
+// patchPath Splits the JSON pointer of a patch into its unescaped tokens
+func patchPath(path string) []string {
+	tokens := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for index, token := range tokens {
+		tokens[index] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens
+}
+
+// redactPatches Copies the patches for logging, redacting env values, secret references and the configured annotations
+func (patcher *SidecarInjectorPatcher) redactPatches(patches []admission.PatchOperation) []admission.PatchOperation {
+	redacted := make([]admission.PatchOperation, len(patches))
+	for index, patch := range patches {
+		redacted[index] = patch
+		if patch.Value == nil {
+			continue
+		}
+		var value interface{}
+		raw, err := json.Marshal(patch.Value)
+		if err == nil {
+			err = json.Unmarshal(raw, &value)
+		}
+		if err != nil {
+			redacted[index].Value = redactedValue
+			continue
+		}
+		redacted[index].Value = patcher.redactValue(patchPath(patch.Path), value)
+	}
+	return redacted
+}
+
+// redactValue Redacts the value at the path of the pod, walking down its objects and lists
+func (patcher *SidecarInjectorPatcher) redactValue(path []string, value interface{}) interface{} {
+	length := len(path)
+	if length >= 3 && path[length-3] == "env" && path[length-1] == "value" {
+		return redactedValue
+	}
+	if length >= 1 && secretReferenceKeys[path[length-1]] {
+		return redactedValue
+	}
+	if length >= 3 && path[length-3] == "metadata" && path[length-2] == "annotations" && slices.Contains(patcher.RedactedAnnotations, path[length-1]) {
+		return redactedValue
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = patcher.redactValue(append(path[:length:length], key), item)
+		}
+	case []interface{}:
+		for index, item := range typed {
+			typed[index] = patcher.redactValue(append(path[:length:length], strconv.Itoa(index)), item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestSidecarInjectorPatcher_redactPatches(t *testing.T) {
+	patcher := &SidecarInjectorPatcher{RedactedAnnotations: []string{"vault.example.com/token"}}
+	container := v1.Container{
+		Name: "envoy",
+		Env: []v1.EnvVar{
+			{Name: "LOG_LEVEL", Value: "info"},
+			{Name: "PASSWORD", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "envoy"}, Key: "password"}}},
+		},
+		EnvFrom: []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "envoy-env"}}}},
+	}
+	patches := []admission.PatchOperation{
+		{Op: "add", Path: "/spec/containers/-", Value: container},
+		{Op: "add", Path: "/spec/containers/0/env", Value: []v1.EnvVar{{Name: "ENVOY_ADMIN_PORT", Value: "15000"}}},
+		{Op: "replace", Path: "/spec/containers/0/env/1/value", Value: "-javaagent:/agent.jar"},
+		{Op: "add", Path: "/spec/volumes/-", Value: v1.Volume{Name: "certs", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "envoy-certs"}}}},
+		{Op: "add", Path: "/metadata/annotations", Value: map[string]string{"vault.example.com/token": "s.abc", "envoy.example.com/port": "15000"}},
+		{Op: "add", Path: "/metadata/annotations/vault.example.com~1token", Value: "s.abc"},
+		{Op: "remove", Path: "/spec/containers/1"},
+	}
+	want := []admission.PatchOperation{
+		{Op: "add", Path: "/spec/containers/-", Value: map[string]interface{}{
+			"name":      "envoy",
+			"resources": map[string]interface{}{},
+			"env": []interface{}{
+				map[string]interface{}{"name": "LOG_LEVEL", "value": redactedValue},
+				map[string]interface{}{"name": "PASSWORD", "valueFrom": map[string]interface{}{"secretKeyRef": redactedValue}},
+			},
+			"envFrom": []interface{}{map[string]interface{}{"secretRef": redactedValue}},
+		}},
+		{Op: "add", Path: "/spec/containers/0/env", Value: []interface{}{map[string]interface{}{"name": "ENVOY_ADMIN_PORT", "value": redactedValue}}},
+		{Op: "replace", Path: "/spec/containers/0/env/1/value", Value: redactedValue},
+		{Op: "add", Path: "/spec/volumes/-", Value: map[string]interface{}{"name": "certs", "secret": redactedValue}},
+		{Op: "add", Path: "/metadata/annotations", Value: map[string]interface{}{"vault.example.com/token": redactedValue, "envoy.example.com/port": "15000"}},
+		{Op: "add", Path: "/metadata/annotations/vault.example.com~1token", Value: redactedValue},
+		{Op: "remove", Path: "/spec/containers/1"},
+	}
+	assert.Equal(t, want, patcher.redactPatches(patches))
+	assert.Equal(t, "info", container.Env[0].Value, "redactPatches() modified the patches")
+}
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ResourceModeFixed Sidecar containers keep the resources of their definition
+	ResourceModeFixed = "fixed"
+	// ResourceModeProportional Sidecar container resources are a ratio of the application resources
+	ResourceModeProportional = "proportional"
+
+	// ResourceBasisPrimary Ratios apply to the primary application container
+	ResourceBasisPrimary = "primary"
+	// ResourceBasisPod Ratios apply to the sum of all application containers
+	ResourceBasisPod = "pod"
+
+	defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+)
+
+// SidecarResourcePolicy Computes the resources of the sidecar containers from the application containers
+type SidecarResourcePolicy struct {
+	Mode  string                          `yaml:"mode"`
+	Basis string                          `yaml:"basis"`
+	Ratio map[corev1.ResourceName]float64 `yaml:"ratio"`
+	Min   corev1.ResourceList             `yaml:"min"`
+	Max   corev1.ResourceList             `yaml:"max"`
+}
+
+// resourceOverrides Pod annotation suffixes overriding the resources of the sidecar containers
+var resourceOverrides = []struct {
+	suffix string
+	name   corev1.ResourceName
+	limit  bool
+}{
+	{suffix: "cpu", name: corev1.ResourceCPU},
+	{suffix: "memory", name: corev1.ResourceMemory},
+	{suffix: "cpu-limit", name: corev1.ResourceCPU, limit: true},
+	{suffix: "memory-limit", name: corev1.ResourceMemory, limit: true},
+}
+
+// sidecarAnnotation Pod annotation configuring a single sidecar, e.g. `sidecar-injector.expedia.com/fluent-bit.cpu`
+func (patcher *SidecarInjectorPatcher) sidecarAnnotation(sidecar Sidecar, suffix string) string {
+	return patcher.InjectPrefix + "/" + sidecar.Name + "." + suffix
+}
+
+// applicationResources Resources the ratios apply to, from the primary container or the whole application
+func applicationResources(pod corev1.Pod, basis string) corev1.ResourceRequirements {
+	if len(pod.Spec.Containers) == 0 {
+		return corev1.ResourceRequirements{}
+	}
+	if basis == ResourceBasisPod {
+		var total corev1.ResourceRequirements
+		for _, container := range pod.Spec.Containers {
+			total.Requests = addResources(total.Requests, container.Resources.Requests)
+			total.Limits = addResources(total.Limits, container.Resources.Limits)
+		}
+		return total
+	}
+	if name, ok := pod.GetAnnotations()[defaultContainerAnnotation]; ok {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == name {
+				return container.Resources
+			}
+		}
+	}
+	return pod.Spec.Containers[0].Resources
+}
+
+// scaleQuantity Multiplies the quantity by the ratio, in millis for CPU to keep fractions of a core
+func scaleQuantity(name corev1.ResourceName, quantity resource.Quantity, ratio float64) resource.Quantity {
+	if name == corev1.ResourceCPU {
+		return *resource.NewMilliQuantity(int64(float64(quantity.MilliValue())*ratio), quantity.Format)
+	}
+	return *resource.NewQuantity(int64(float64(quantity.Value())*ratio), quantity.Format)
+}
+
+// clampQuantity Keeps the quantity between the floor and ceiling of the policy
+func (policy SidecarResourcePolicy) clampQuantity(name corev1.ResourceName, quantity resource.Quantity) resource.Quantity {
+	if min, ok := policy.Min[name]; ok && quantity.Cmp(min) < 0 {
+		return min.DeepCopy()
+	}
+	if max, ok := policy.Max[name]; ok && quantity.Cmp(max) > 0 {
+		return max.DeepCopy()
+	}
+	return quantity
+}
+
+// proportionalResources Computes requests and limits as a ratio of the application resources
+func (policy SidecarResourcePolicy) proportionalResources(application corev1.ResourceRequirements, resources corev1.ResourceRequirements) corev1.ResourceRequirements {
+	resources = *resources.DeepCopy()
+	for name, ratio := range policy.Ratio {
+		if request, ok := application.Requests[name]; ok {
+			resources.Requests = setResource(resources.Requests, name, policy.clampQuantity(name, scaleQuantity(name, request, ratio)))
+		} else if min, ok := policy.Min[name]; ok {
+			resources.Requests = setResource(resources.Requests, name, min.DeepCopy())
+		}
+		if limit, ok := application.Limits[name]; ok {
+			resources.Limits = setResource(resources.Limits, name, policy.clampQuantity(name, scaleQuantity(name, limit, ratio)))
+		}
+	}
+	return resources
+}
+
+func setResource(resources corev1.ResourceList, name corev1.ResourceName, quantity resource.Quantity) corev1.ResourceList {
+	if resources == nil {
+		resources = corev1.ResourceList{}
+	}
+	resources[name] = quantity
+	return resources
+}
+
+// applyResourcePolicy Computes the resources of the sidecar containers and applies the pod annotation overrides
+func (patcher *SidecarInjectorPatcher) applyResourcePolicy(pod corev1.Pod, sidecar Sidecar) (Sidecar, error) {
+	containers := make([]corev1.Container, len(sidecar.Containers))
+	copy(containers, sidecar.Containers)
+	if policy := sidecar.ResourcePolicy; policy != nil && policy.Mode == ResourceModeProportional {
+		application := applicationResources(pod, policy.Basis)
+		for index := range containers {
+			containers[index].Resources = policy.proportionalResources(application, containers[index].Resources)
+		}
+	}
+	for _, override := range resourceOverrides {
+		annotation := patcher.sidecarAnnotation(sidecar, override.suffix)
+		value, ok := pod.GetAnnotations()[annotation]
+		if !ok {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return sidecar, fmt.Errorf("invalid quantity %q in annotation %s: %v", value, annotation, err)
+		}
+		for index := range containers {
+			resources := containers[index].Resources.DeepCopy()
+			if override.limit {
+				resources.Limits = setResource(resources.Limits, override.name, quantity)
+			} else {
+				resources.Requests = setResource(resources.Requests, override.name, quantity)
+			}
+			containers[index].Resources = *resources
+		}
+	}
+	if sidecar.Containers != nil {
+		sidecar.Containers = containers
+	}
+	return sidecar, nil
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSidecarInjectorPatcher_applyResourcePolicy(t *testing.T) {
+	app := func(name string, cpu string, memory string) v1.Container {
+		return v1.Container{Name: name, Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+			Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse(memory)},
+		}}
+	}
+	pod := func(annotations map[string]string) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+			Spec:       v1.PodSpec{Containers: []v1.Container{app("app", "2", "1Gi"), app("worker", "1", "512Mi")}},
+		}
+	}
+	proportional := &SidecarResourcePolicy{
+		Mode:  ResourceModeProportional,
+		Ratio: map[v1.ResourceName]float64{v1.ResourceCPU: 0.1, v1.ResourceMemory: 0.25},
+		Min:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("50m")},
+		Max:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("200Mi")},
+	}
+	tests := []struct {
+		name         string
+		pod          v1.Pod
+		policy       *SidecarResourcePolicy
+		wantRequests v1.ResourceList
+		wantLimits   v1.ResourceList
+		wantErr      assert.ErrorAssertionFunc
+	}{
+		{
+			name:         "fixed resources",
+			pod:          pod(nil),
+			wantRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m")},
+			wantErr:      assert.NoError,
+		},
+		{
+			name:   "proportional to primary container",
+			pod:    pod(nil),
+			policy: proportional,
+			wantRequests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("200m"),
+				v1.ResourceMemory: resource.MustParse("200Mi"),
+			},
+			wantLimits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("200Mi")},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:   "proportional to default container",
+			pod:    pod(map[string]string{"kubectl.kubernetes.io/default-container": "worker"}),
+			policy: proportional,
+			wantRequests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("100m"),
+				v1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+			wantLimits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")},
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "proportional to pod with floor",
+			pod:  pod(nil),
+			policy: &SidecarResourcePolicy{
+				Mode:  ResourceModeProportional,
+				Basis: ResourceBasisPod,
+				Ratio: map[v1.ResourceName]float64{v1.ResourceCPU: 0.01},
+				Min:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("50m")},
+			},
+			wantRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("50m")},
+			wantErr:      assert.NoError,
+		},
+		{
+			name: "annotation overrides",
+			pod: pod(map[string]string{
+				"sidecar-injector.expedia.com/agent.cpu":          "300m",
+				"sidecar-injector.expedia.com/agent.memory-limit": "64Mi",
+			}),
+			policy: proportional,
+			wantRequests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("300m"),
+				v1.ResourceMemory: resource.MustParse("200Mi"),
+			},
+			wantLimits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Mi")},
+			wantErr:    assert.NoError,
+		},
+		{
+			name:    "invalid annotation override",
+			pod:     pod(map[string]string{"sidecar-injector.expedia.com/agent.cpu": "a lot"}),
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{InjectPrefix: "sidecar-injector.expedia.com"}
+			sidecar := Sidecar{
+				Name: "agent",
+				Containers: []v1.Container{{Name: "agent", Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m")},
+				}}},
+				ResourcePolicy: tt.policy,
+			}
+			got, err := patcher.applyResourcePolicy(tt.pod, sidecar)
+			if !tt.wantErr(t, err, "applyResourcePolicy(%v)", sidecar) || err != nil {
+				return
+			}
+			resources := got.Containers[0].Resources
+			assert.Equal(t, len(tt.wantRequests), len(resources.Requests))
+			for name, want := range tt.wantRequests {
+				assert.Zerof(t, want.Cmp(resources.Requests[name]), "requests.%s = %s, want %s", name, resources.Requests.Name(name, resource.DecimalSI), want.String())
+			}
+			assert.Equal(t, len(tt.wantLimits), len(resources.Limits))
+			for name, want := range tt.wantLimits {
+				assert.Zerof(t, want.Cmp(resources.Limits[name]), "limits.%s = %s, want %s", name, resources.Limits.Name(name, resource.DecimalSI), want.String())
+			}
+			assert.Equal(t, resource.MustParse("10m"), sidecar.Containers[0].Resources.Requests[v1.ResourceCPU], "input modified")
+		})
+	}
+}
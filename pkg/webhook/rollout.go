@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"hash/fnv"
+	"reflect"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultStableRevision = "stable"
+	defaultCanaryRevision = "canary"
+)
+
+// SidecarRollout Gradually shifts workloads from the sidecar spec to a canary revision of it
+type SidecarRollout struct {
+	Canary *Sidecar `yaml:"canary"`
+	Weight int      `yaml:"weight"`
+}
+
+// workloadKey Identifies the workload owning the pod so that all of its replicas hash the same
+func workloadKey(namespace string, pod corev1.Pod) string {
+	for _, owner := range pod.GetOwnerReferences() {
+		if owner.Controller != nil && *owner.Controller {
+			return namespace + "/" + owner.Kind + "/" + owner.Name
+		}
+	}
+	if pod.GetGenerateName() != "" {
+		return namespace + "/" + pod.GetGenerateName()
+	}
+	return namespace + "/" + pod.GetName()
+}
+
+// rolloutBucket Deterministically places the workload in one of 100 buckets for the sidecar
+func rolloutBucket(sidecarName string, workload string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(sidecarName + "/" + workload))
+	return int(hash.Sum32() % 100)
+}
+
+// selectSidecarRevision Selects the stable or canary revision of the sidecar for the workload of the pod
+func selectSidecarRevision(namespace string, pod corev1.Pod, sidecar Sidecar) Sidecar {
+	if sidecar.Rollout == nil || sidecar.Rollout.Canary == nil {
+		return sidecar
+	}
+	if rolloutBucket(sidecar.Name, workloadKey(namespace, pod)) >= sidecar.Rollout.Weight {
+		if sidecar.Revision == "" {
+			sidecar.Revision = defaultStableRevision
+		}
+		sidecar.Rollout = nil
+		return sidecar
+	}
+	canary := canaryRevision(sidecar)
+	if canary.Revision == "" {
+		canary.Revision = defaultCanaryRevision
+	}
+	return canary
+}
+
+// canaryRevision Merges the fields set on the canary over a copy of the sidecar, keeping its name, version and the
+// state of the reference it was resolved from
+func canaryRevision(sidecar Sidecar) Sidecar {
+	canary := sidecar
+	canary.Revision = ""
+	canary.Rollout = nil
+	overrides := reflect.ValueOf(*sidecar.Rollout.Canary)
+	merged := reflect.ValueOf(&canary).Elem()
+	for index := 0; index < overrides.NumField(); index++ {
+		field := overrides.Type().Field(index)
+		if !field.IsExported() || field.Name == "Name" || field.Name == "Version" || field.Name == "Rollout" {
+			continue
+		}
+		if value := overrides.Field(index); !value.IsZero() {
+			merged.Field(index).Set(value)
+		}
+	}
+	return canary
+}
+
+// injectedRevisionsAnnotation Annotation recording the revisions of the injected sidecars under rollout
+func (patcher *SidecarInjectorPatcher) injectedRevisionsAnnotation() string {
+	return patcher.InjectPrefix + "/injected-revisions"
+}
+
+// injectedRevisions Formats the sidecars with a revision for the injected revisions annotation
+func injectedRevisions(sidecars []Sidecar) string {
+	var revisions []string
+	for _, sidecar := range sidecars {
+		if sidecar.Revision != "" {
+			revisions = append(revisions, sidecar.Name+"="+sidecar.Revision)
+		}
+	}
+	return strings.Join(lo.Uniq(revisions), ",")
+}
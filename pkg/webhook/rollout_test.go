@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_workloadKey(t *testing.T) {
+	controller := true
+	tests := []struct {
+		name string
+		pod  v1.Pod
+		want string
+	}{
+		{
+			name: "replicaset owner",
+			pod: v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:         "app-7d9f8-x2x9z",
+				GenerateName: "app-7d9f8-",
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "ReplicaSet", Name: "app-7d9f8", Controller: &controller},
+				},
+			}},
+			want: "test/ReplicaSet/app-7d9f8",
+		},
+		{
+			name: "statefulset owner",
+			pod: v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "db-0",
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "StatefulSet", Name: "db", Controller: &controller},
+				},
+			}},
+			want: "test/StatefulSet/db",
+		},
+		{
+			name: "generate name fallback",
+			pod:  v1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "job-"}},
+			want: "test/job-",
+		},
+		{
+			name: "name fallback",
+			pod:  v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "standalone"}},
+			want: "test/standalone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, workloadKey("test", tt.pod), "workloadKey(%v)", tt.pod)
+		})
+	}
+}
+
+func Test_selectSidecarRevision(t *testing.T) {
+	sidecar := func(weight int) Sidecar {
+		return Sidecar{
+			Name:       "fluent-bit",
+			Version:    "2.1.0",
+			Containers: []v1.Container{{Name: "fluent-bit", Image: "fluent-bit:a"}},
+			Rollout: &SidecarRollout{
+				Weight: weight,
+				Canary: &Sidecar{
+					Revision:   "b",
+					Containers: []v1.Container{{Name: "fluent-bit", Image: "fluent-bit:b"}},
+				},
+			},
+		}
+	}
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "app-"}}
+	tests := []struct {
+		name         string
+		sidecar      Sidecar
+		wantImage    string
+		wantRevision string
+	}{
+		{name: "no rollout", sidecar: Sidecar{Containers: []v1.Container{{Image: "agent"}}}, wantImage: "agent"},
+		{name: "no weight on canary", sidecar: sidecar(0), wantImage: "fluent-bit:a", wantRevision: "stable"},
+		{name: "full weight on canary", sidecar: sidecar(100), wantImage: "fluent-bit:b", wantRevision: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectSidecarRevision("test", pod, tt.sidecar)
+			assert.Equal(t, tt.wantImage, got.Containers[0].Image)
+			assert.Equal(t, tt.wantRevision, got.Revision)
+			assert.Nil(t, got.Rollout)
+			assert.Equal(t, tt.sidecar.Name, got.Name)
+		})
+	}
+}
+
+func Test_rolloutBucket(t *testing.T) {
+	controller := true
+	replica := func(name string) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-7d9f8", Controller: &controller}},
+		}}
+	}
+	first := rolloutBucket("fluent-bit", workloadKey("test", replica("app-7d9f8-aaaaa")))
+	second := rolloutBucket("fluent-bit", workloadKey("test", replica("app-7d9f8-bbbbb")))
+	assert.Equal(t, first, second, "replicas of one workload must select the same revision")
+	assert.GreaterOrEqual(t, first, 0)
+	assert.Less(t, first, 100)
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateCanary(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: agent
+                       deprecated: true
+                       replacement: agent-v2
+                       parameters:
+                         - name: level
+                           default: info
+                       containers:
+                         - name: agent
+                           image: busybox:{{ .Params.level }}
+                       rollout:
+                         weight: 100
+                         canary:
+                           containers:
+                             - name: agent
+                               image: busybox:{{ .Params.level }}-canary`,
+		},
+	}
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "app-",
+			Annotations:  map[string]string{"sidecar-injector.expedia.com/inject": "agent(level=debug)"},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+	}
+	patcher := &SidecarInjectorPatcher{
+		K8sClient:      fake.NewSimpleClientset(configmap),
+		InjectPrefix:   "sidecar-injector.expedia.com",
+		InjectName:     "inject",
+		SidecarDataKey: "sidecars.yaml",
+	}
+	ctx := admission.WithWarnings(context.Background())
+	got, err := patcher.PatchPodCreate(ctx, "test", pod)
+	assert.NoError(t, err)
+	assert.Equal(t, []admission.PatchOperation{
+		{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "agent", Image: "busybox:debug-canary"}},
+		{Op: "add", Path: "/metadata/annotations/sidecar-injector.expedia.com~1injected-revisions", Value: "agent=canary"},
+	}, got)
+	assert.Equal(t, []string{"sidecar agent from configmap agent is deprecated, use agent-v2 instead"}, admission.Warnings(ctx))
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ghodss/yaml"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// SidecarAPIVersionV1 Implicit version of a list of sidecars or a single sidecar without header
+	SidecarAPIVersionV1 = "sidecar-injector.expedia.com/v1"
+	// SidecarAPIVersionV2 Sidecars listed under `sidecars` with an `apiVersion` header
+	SidecarAPIVersionV2 = "sidecar-injector.expedia.com/v2"
+	// LatestSidecarAPIVersion Version sidecar definitions are converted to
+	LatestSidecarAPIVersion = SidecarAPIVersionV2
+)
+
+// sidecarAPIVersions Versions of the sidecar schema, oldest first
+var sidecarAPIVersions = []string{SidecarAPIVersionV1, SidecarAPIVersionV2}
+
+// sidecarConversions Converts the raw sidecar definitions of a version to the next version
+var sidecarConversions = map[string]func([]interface{}) ([]interface{}, error){
+	SidecarAPIVersionV1: convertV1ToV2,
+}
+
+// sidecarDocument Versioned document of sidecar definitions
+type sidecarDocument struct {
+	APIVersion string        `json:"apiVersion"`
+	Sidecars   []interface{} `json:"sidecars"`
+}
+
+// convertV1ToV2 The sidecar fields are unchanged in v2, which only adds the document header
+func convertV1ToV2(sidecars []interface{}) ([]interface{}, error) {
+	return sidecars, nil
+}
+
+// versionedSidecars Splits a parsed document into its schema version and raw sidecar definitions
+func versionedSidecars(document interface{}) (string, []interface{}, error) {
+	switch parsed := document.(type) {
+	case []interface{}:
+		return SidecarAPIVersionV1, parsed, nil
+	case map[string]interface{}:
+		apiVersion, ok := parsed["apiVersion"]
+		if !ok {
+			return SidecarAPIVersionV1, []interface{}{parsed}, nil
+		}
+		sidecars, ok := parsed["sidecars"].([]interface{})
+		if !ok && parsed["sidecars"] != nil {
+			return "", nil, fmt.Errorf("sidecars of %v must be a list", apiVersion)
+		}
+		return fmt.Sprint(apiVersion), sidecars, nil
+	default:
+		return "", nil, fmt.Errorf("expected a sidecar, a list of sidecars or a versioned document")
+	}
+}
+
+// convertSidecars Converts raw sidecar definitions from their version to the latest version
+func convertSidecars(apiVersion string, sidecars []interface{}) ([]interface{}, error) {
+	start := -1
+	for index, version := range sidecarAPIVersions {
+		if version == apiVersion {
+			start = index
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("unknown sidecar apiVersion %s", apiVersion)
+	}
+	var err error
+	for _, version := range sidecarAPIVersions[start : len(sidecarAPIVersions)-1] {
+		if sidecars, err = sidecarConversions[version](sidecars); err != nil {
+			return nil, fmt.Errorf("converting from %s: %v", version, err)
+		}
+	}
+	return sidecars, nil
+}
+
+// latestSidecarDefinitions Reads the YAML or JSON documents of the content, converted to the latest version
+func latestSidecarDefinitions(content []byte) ([]interface{}, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	var sidecars []interface{}
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return sidecars, nil
+		} else if err != nil {
+			return nil, err
+		}
+		var parsed interface{}
+		if err := yaml.Unmarshal(document, &parsed); err != nil {
+			return nil, err
+		}
+		if parsed == nil {
+			continue
+		}
+		apiVersion, definitions, err := versionedSidecars(parsed)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := convertSidecars(apiVersion, definitions)
+		if err != nil {
+			return nil, err
+		}
+		sidecars = append(sidecars, converted...)
+	}
+}
+
+// MigrateSidecars Rewrites sidecar definitions of any version as a single document of the latest version
+func MigrateSidecars(content []byte) ([]byte, error) {
+	sidecars, err := latestSidecarDefinitions(content)
+	if err != nil {
+		return nil, err
+	}
+	if sidecars == nil {
+		sidecars = []interface{}{}
+	}
+	return yaml.Marshal(sidecarDocument{APIVersion: LatestSidecarAPIVersion, Sidecars: sidecars})
+}
+
+// parseSidecarDocuments Parses the sidecars of YAML or JSON content of any version, where each YAML document is a
+// sidecar, a list of sidecars or a versioned document
+func parseSidecarDocuments(content []byte) ([]Sidecar, error) {
+	definitions, err := latestSidecarDefinitions(content)
+	if err != nil || definitions == nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(definitions)
+	if err != nil {
+		return nil, err
+	}
+	return parseSidecars(encoded)
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseSidecarDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{name: "implicit v1 list", content: "- name: envoy\n- name: logs\n", want: []string{"envoy", "logs"}, wantErr: assert.NoError},
+		{name: "implicit v1 sidecar", content: `{"name": "envoy"}`, want: []string{"envoy"}, wantErr: assert.NoError},
+		{name: "explicit v1", content: "apiVersion: sidecar-injector.expedia.com/v1\nsidecars:\n- name: envoy\n", want: []string{"envoy"}, wantErr: assert.NoError},
+		{name: "v2", content: "apiVersion: sidecar-injector.expedia.com/v2\nsidecars:\n- name: envoy\n---\n- name: logs\n", want: []string{"envoy", "logs"}, wantErr: assert.NoError},
+		{name: "unknown version", content: "apiVersion: sidecar-injector.expedia.com/v9\nsidecars: []\n", wantErr: assert.Error},
+		{name: "scalar", content: "envoy", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sidecars, err := parseSidecarDocuments([]byte(tt.content))
+			if !tt.wantErr(t, err, "parseSidecarDocuments(%v)", tt.content) || err != nil {
+				return
+			}
+			var got []string
+			for _, sidecar := range sidecars {
+				got = append(got, sidecar.Name)
+			}
+			assert.Equalf(t, tt.want, got, "parseSidecarDocuments(%v)", tt.content)
+		})
+	}
+}
+
+func TestMigrateSidecars(t *testing.T) {
+	got, err := MigrateSidecars([]byte("- name: envoy\n  version: 1.0.0\n---\nname: logs\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "apiVersion: sidecar-injector.expedia.com/v2\nsidecars:\n- name: envoy\n  version: 1.0.0\n- name: logs\n", string(got))
+
+	_, err = MigrateSidecars([]byte("apiVersion: v0\n"))
+	assert.Error(t, err)
+}
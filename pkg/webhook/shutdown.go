@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ShutdownOrderLast The sidecar containers stop after the application containers
+	ShutdownOrderLast = "last"
+	// ShutdownOrderFirst The sidecar containers stop before the application containers
+	ShutdownOrderFirst = "first"
+	// defaultTerminationGracePeriodSeconds Grace period of pods not setting one
+	defaultTerminationGracePeriodSeconds = int64(corev1.DefaultTerminationGracePeriodSeconds)
+)
+
+// SidecarShutdown Shutdown ordering of a sidecar relative to the application containers
+type SidecarShutdown struct {
+	Order        string   `yaml:"order"`
+	DelaySeconds int64    `yaml:"delaySeconds"`
+	Command      []string `yaml:"command"`
+}
+
+// shutdownScript Shell script delaying the stop of the containers stopping later
+func shutdownScript(shutdown *SidecarShutdown) string {
+	if len(shutdown.Command) > 0 {
+		return shellJoin(shutdown.Command)
+	}
+	return fmt.Sprintf("sleep %d", shutdown.DelaySeconds)
+}
+
+// shellJoin Quotes the arguments of a command for /bin/sh
+func shellJoin(command []string) string {
+	quoted := make([]string, 0, len(command))
+	for _, argument := range command {
+		quoted = append(quoted, "'"+strings.ReplaceAll(argument, "'", `'\''`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// wrapHook Runs the script before the existing lifecycle hook of a container
+func wrapHook(existing *corev1.LifecycleHandler, script string) (*corev1.LifecycleHandler, error) {
+	if existing == nil {
+		return &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", script}}}, nil
+	}
+	if existing.Exec == nil {
+		return nil, fmt.Errorf("only exec hooks can be wrapped")
+	}
+	return &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", script + "; " + shellJoin(existing.Exec.Command)}}}, nil
+}
+
+// withPreStop Copy of the container with the script wrapped around its preStop hook
+func withPreStop(container corev1.Container, script string) (corev1.Container, error) {
+	lifecycle := corev1.Lifecycle{}
+	if container.Lifecycle != nil {
+		lifecycle = *container.Lifecycle
+	}
+	preStop, err := wrapHook(lifecycle.PreStop, script)
+	if err != nil {
+		return container, err
+	}
+	lifecycle.PreStop = preStop
+	container.Lifecycle = &lifecycle
+	return container, nil
+}
+
+// applyShutdownOrder Delays the stop of the sidecar containers of a sidecar stopping last
+func applyShutdownOrder(ctx context.Context, source string, sidecar Sidecar) Sidecar {
+	if sidecar.Shutdown == nil || sidecar.Shutdown.Order == ShutdownOrderFirst {
+		return sidecar
+	}
+	script := shutdownScript(sidecar.Shutdown)
+	containers := make([]corev1.Container, 0, len(sidecar.Containers))
+	for _, container := range sidecar.Containers {
+		wrapped, err := withPreStop(container, script)
+		if err != nil {
+			warnLifecycle(ctx, fmt.Sprintf("%s cannot order the shutdown of container %s: %v", source, container.Name, err))
+		}
+		containers = append(containers, wrapped)
+	}
+	sidecar.Containers = containers
+	return sidecar
+}
+
+// shutdownPatches Delays the stop of the application containers for a sidecar stopping first, and raises the
+// termination grace period of the pod by the shutdown delay
+func shutdownPatches(ctx context.Context, source string, pod *corev1.Pod, applications []corev1.Container, sidecar Sidecar) []admission.PatchOperation {
+	if sidecar.Shutdown == nil {
+		return nil
+	}
+	var patches []admission.PatchOperation
+	if sidecar.Shutdown.Order == ShutdownOrderFirst {
+		script := shutdownScript(sidecar.Shutdown)
+		for _, application := range applications {
+			index := containerIndex(pod.Spec.Containers, application.Name)
+			if index < 0 {
+				continue
+			}
+			container := pod.Spec.Containers[index]
+			wrapped, err := withPreStop(container, script)
+			if err != nil {
+				warnLifecycle(ctx, fmt.Sprintf("%s cannot order the shutdown of container %s: %v", source, container.Name, err))
+				continue
+			}
+			patches = append(patches, lifecyclePatch(index, container.Lifecycle, wrapped.Lifecycle))
+			pod.Spec.Containers[index] = wrapped
+		}
+	}
+	if sidecar.Shutdown.DelaySeconds > 0 {
+		gracePeriod := defaultTerminationGracePeriodSeconds
+		op := "add"
+		if pod.Spec.TerminationGracePeriodSeconds != nil {
+			gracePeriod = *pod.Spec.TerminationGracePeriodSeconds
+			op = "replace"
+		}
+		gracePeriod += sidecar.Shutdown.DelaySeconds
+		patches = append(patches, admission.PatchOperation{Op: op, Path: "/spec/terminationGracePeriodSeconds", Value: gracePeriod})
+		pod.Spec.TerminationGracePeriodSeconds = &gracePeriod
+	}
+	return patches
+}
+
+func lifecyclePatch(index int, existing *corev1.Lifecycle, wrapped *corev1.Lifecycle) admission.PatchOperation {
+	path := fmt.Sprintf("/spec/containers/%d/lifecycle", index)
+	switch {
+	case existing == nil:
+		return admission.PatchOperation{Op: "add", Path: path, Value: wrapped}
+	case existing.PreStop == nil:
+		return admission.PatchOperation{Op: "add", Path: path + "/preStop", Value: wrapped.PreStop}
+	default:
+		return admission.PatchOperation{Op: "replace", Path: path + "/preStop", Value: wrapped.PreStop}
+	}
+}
+
+func warnLifecycle(ctx context.Context, message string) {
+	admission.Logger(ctx).Warn(message)
+	admission.AddWarning(ctx, message)
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_wrapHook(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *v1.LifecycleHandler
+		want     *v1.LifecycleHandler
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "no hook",
+			want:    &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c", "sleep 5"}}},
+			wantErr: assert.NoError,
+		},
+		{
+			name:     "exec hook",
+			existing: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"nginx", "-s", "quit"}}},
+			want:     &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c", "sleep 5; 'nginx' '-s' 'quit'"}}},
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "http hook",
+			existing: &v1.LifecycleHandler{HTTPGet: &v1.HTTPGetAction{Path: "/shutdown"}},
+			wantErr:  assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wrapHook(tt.existing, "sleep 5")
+			if !tt.wantErr(t, err, "wrapHook(%v)", tt.existing) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "wrapHook(%v)", tt.existing)
+		})
+	}
+}
+
+func Test_shellJoin(t *testing.T) {
+	assert.Equal(t, `'/bin/drain' '--message' 'it'\''s over'`, shellJoin([]string{"/bin/drain", "--message", "it's over"}))
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateShutdown(t *testing.T) {
+	gracePeriod := int64(60)
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "envoy"},
+		},
+		Spec: v1.PodSpec{
+			TerminationGracePeriodSeconds: &gracePeriod,
+			Containers: []v1.Container{
+				{Name: "app"},
+				{Name: "worker", Lifecycle: &v1.Lifecycle{PreStop: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"stop"}}}}},
+			},
+		},
+	}
+	tests := []struct {
+		name  string
+		order string
+		want  []admission.PatchOperation
+	}{
+		{
+			name:  "sidecar stops last",
+			order: ShutdownOrderLast,
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "envoy", Lifecycle: &v1.Lifecycle{
+					PreStop: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c", "sleep 10"}}},
+				}}},
+				{Op: "replace", Path: "/spec/terminationGracePeriodSeconds", Value: int64(70)},
+			},
+		},
+		{
+			name:  "sidecar stops first",
+			order: ShutdownOrderFirst,
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "envoy"}},
+				{Op: "add", Path: "/spec/containers/0/lifecycle", Value: &v1.Lifecycle{
+					PreStop: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c", "sleep 10"}}},
+				}},
+				{Op: "replace", Path: "/spec/containers/1/lifecycle/preStop", Value: &v1.LifecycleHandler{
+					Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c", "sleep 10; 'stop'"}},
+				}},
+				{Op: "replace", Path: "/spec/terminationGracePeriodSeconds", Value: int64(70)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configmap := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "envoy", Namespace: "test"},
+				Data: map[string]string{"sidecars.yaml": `
+                     - name: envoy
+                       shutdown:
+                         order: ` + tt.order + `
+                         delaySeconds: 10
+                       containers:
+                         - name: envoy`,
+				},
+			}
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:      fake.NewSimpleClientset(configmap),
+				InjectPrefix:   "sidecar-injector.expedia.com",
+				InjectName:     "inject",
+				SidecarDataKey: "sidecars.yaml",
+			}
+			got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,44 +2,96 @@ package webhook
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
-	"github.com/ghodss/yaml"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/pod-security-admission/api"
 )
 
 // Sidecar Kubernetes Sidecar Injector schema
 type Sidecar struct {
-	Name             string                        `yaml:"name"`
-	InitContainers   []corev1.Container            `yaml:"initContainers"`
-	Containers       []corev1.Container            `yaml:"containers"`
-	Volumes          []corev1.Volume               `yaml:"volumes"`
-	ImagePullSecrets []corev1.LocalObjectReference `yaml:"imagePullSecrets"`
-	Annotations      map[string]string             `yaml:"annotations"`
-	Labels           map[string]string             `yaml:"labels"`
+	Name                      string                        `yaml:"name"`
+	InitContainers            []corev1.Container            `yaml:"initContainers"`
+	Containers                []corev1.Container            `yaml:"containers"`
+	Volumes                   []corev1.Volume               `yaml:"volumes"`
+	ImagePullSecrets          []corev1.LocalObjectReference `yaml:"imagePullSecrets"`
+	Annotations               map[string]string             `yaml:"annotations"`
+	Labels                    map[string]string             `yaml:"labels"`
+	Version                   string                        `yaml:"version"`
+	Default                   bool                          `yaml:"default"`
+	Revision                  string                        `yaml:"revision"`
+	Rollout                   *SidecarRollout               `yaml:"rollout"`
+	Deprecated                bool                          `yaml:"deprecated"`
+	Replacement               string                        `yaml:"replacement"`
+	Sunset                    string                        `yaml:"sunset"`
+	ResourcePolicy            *SidecarResourcePolicy        `yaml:"resourcePolicy"`
+	AutoPorts                 []SidecarAutoPort             `yaml:"autoPorts"`
+	Shutdown                  *SidecarShutdown              `yaml:"shutdown"`
+	HoldApplicationUntilReady bool                          `yaml:"holdApplicationUntilReady"`
+	ReadinessHook             *SidecarReadinessHook         `yaml:"readinessHook"`
+	Bootstrap                 *SidecarBootstrap             `yaml:"bootstrap"`
+	PerContainer              *SidecarPerContainer          `yaml:"perContainer"`
+	Variants                  []SidecarVariant              `yaml:"variants"`
+	Parameters                []SidecarParameter            `yaml:"parameters"`
+	Extends                   string                        `yaml:"extends"`
+	ConflictStrategy          string                        `yaml:"conflictStrategy"`
+
+	configmap   string
+	params      map[string]string
+	definition  map[string]interface{}
+	replaces    map[string]bool
+	inline      bool
+	application string
 }
 
 // SidecarInjectorPatcher Sidecar Injector patcher
 type SidecarInjectorPatcher struct {
 	K8sClient                kubernetes.Interface
+	PodLister                corelisters.PodLister
 	InjectPrefix             string
 	InjectName               string
 	SidecarDataKey           string
 	AllowAnnotationOverrides bool
 	AllowLabelOverrides      bool
+	PodSecurityAction        string
+	SunsetAction             string
+	PodBudget                ResourceBudget
+	PodBudgets               bool
+	NamespaceBudget          ResourceBudget
+	NamespaceBudgets         bool
+	BudgetAction             string
+	LimitRangePolicy         string
+	DefaultResources         ResourceDefaults
+	PortConflictAction       string
+	AutoPortRange            PortRange
+	NameCollisionPolicy      string
+	DefaultPlatform          string
+	ExtendsNamespaces        []string
+	ImageRegistryRewrites    map[string]string
+	AllowedImageRegistries   []string
+	RequireImageDigest       bool
+	EventRecorder            record.EventRecorder
+	MetricsLabels            []string
+	RedactedAnnotations      []string
 }
 
 func (patcher *SidecarInjectorPatcher) sideCarInjectionAnnotation() string {
 	return patcher.InjectPrefix + "/" + patcher.InjectName
 }
 
-func (patcher *SidecarInjectorPatcher) configmapSidecarNames(namespace string, pod corev1.Pod) []string {
+func (patcher *SidecarInjectorPatcher) configmapSidecarNames(ctx context.Context, namespace string, pod corev1.Pod) []string {
 	podName := pod.GetName()
 	if podName == "" {
 		podName = pod.GetGenerateName()
@@ -49,16 +101,14 @@ func (patcher *SidecarInjectorPatcher) configmapSidecarNames(namespace string, p
 		annotations = pod.GetAnnotations()
 	}
 	if sidecars, ok := annotations[patcher.sideCarInjectionAnnotation()]; ok {
-		parts := lo.Map[string, string](strings.Split(sidecars, ","), func(part string, _ int) string {
-			return strings.TrimSpace(part)
-		})
+		parts := splitReferences(sidecars)
 
 		if len(parts) > 0 {
-			log.Infof("sideCar injection for %v/%v: sidecars: %v", namespace, podName, sidecars)
+			admission.Logger(ctx).Infof("sideCar injection for %v/%v: sidecars: %v", namespace, podName, sidecars)
 			return parts
 		}
 	}
-	log.Infof("Skipping mutation for [%v]. No action required", pod.GetName())
+	admission.Logger(ctx).Infof("Skipping mutation for [%v]. No action required", pod.GetName())
 	return nil
 }
 
@@ -85,6 +135,9 @@ func createArrayPatches[T any](newCollection []T, existingCollection []T, path s
 
 func createObjectPatches(newMap map[string]string, existingMap map[string]string, path string, override bool) []admission.PatchOperation {
 	var patches []admission.PatchOperation
+	if len(newMap) == 0 {
+		return patches
+	}
 	if existingMap == nil {
 		patches = append(patches, admission.PatchOperation{
 			Op:    "add",
@@ -117,42 +170,416 @@ func escapeJSONPath(k string) string {
 	return strings.ReplaceAll(k, "/", "~1")
 }
 
+// podInjection State of the injection into a pod, the injected pod reflects the patches created so far
+type podInjection struct {
+	namespace       string
+	pod             corev1.Pod
+	ns              *corev1.Namespace
+	podSecurity     api.LevelVersion
+	containerLimits []corev1.LimitRangeItem
+	injected        *corev1.Pod
+	sidecars        []Sidecar
+	patches         []admission.PatchOperation
+	// held Number of sidecar containers inserted before the application containers
+	held int
+}
+
 // PatchPodCreate Handle Pod Create Patch
 func (patcher *SidecarInjectorPatcher) PatchPodCreate(ctx context.Context, namespace string, pod corev1.Pod) ([]admission.PatchOperation, error) {
+	podName := pod.GetName()
+	if podName == "" {
+		podName = pod.GetGenerateName()
+	}
+	ctx, span := tracer.Start(ctx, "PatchPodCreate", trace.WithAttributes(semconv.K8SNamespaceName(namespace), semconv.K8SPodName(podName)))
+	patches, err := patcher.patchPodCreate(ctx, namespace, pod)
+	patcher.injectionEvents(ctx, namespace, pod, err)
+	endSpan(span, err)
+	return patches, err
+}
+
+func (patcher *SidecarInjectorPatcher) patchPodCreate(ctx context.Context, namespace string, pod corev1.Pod) ([]admission.PatchOperation, error) {
 	podName := pod.GetName()
 	if podName == "" {
 		podName = pod.GetGenerateName()
 	}
 	var patches []admission.PatchOperation
-	if configmapSidecarNames := patcher.configmapSidecarNames(namespace, pod); configmapSidecarNames != nil {
+	configmapSidecarNames := patcher.configmapSidecarNames(ctx, namespace, pod)
+	if _, inline := pod.Annotations[patcher.inlineSidecarsAnnotation()]; configmapSidecarNames != nil || inline {
+		injection := &podInjection{
+			namespace: namespace,
+			pod:       pod,
+			injected:  pod.DeepCopy(),
+		}
+		if patcher.namespaceRequired(pod) {
+			injection.ns = patcher.fetchNamespace(ctx, namespace)
+		}
+		injection.podSecurity = patcher.namespacePodSecurity(ctx, injection.ns)
+		injection.containerLimits = patcher.namespaceContainerLimits(ctx, namespace)
+		var sidecars []Sidecar
 		for _, configmapSidecarName := range configmapSidecarNames {
-			configmapSidecar, err := patcher.K8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, configmapSidecarName, metav1.GetOptions{})
-			if k8serrors.IsNotFound(err) {
-				log.Warnf("sidecar configmap %s/%s was not found", namespace, configmapSidecarName)
-			} else if err != nil {
-				log.Errorf("error fetching sidecar configmap %s/%s - %v", namespace, configmapSidecarName, err)
-			} else if sidecarsStr, ok := configmapSidecar.Data[patcher.SidecarDataKey]; ok {
-				var sidecars []Sidecar
-				if err := yaml.Unmarshal([]byte(sidecarsStr), &sidecars); err != nil {
-					log.Errorf("error unmarshalling %s from configmap %s/%s", patcher.SidecarDataKey, pod.GetNamespace(), configmapSidecarName)
-				}
-				if sidecars != nil {
-					for _, sidecar := range sidecars {
-						patches = append(patches, createArrayPatches(sidecar.InitContainers, pod.Spec.InitContainers, "/spec/initContainers")...)
-						patches = append(patches, createArrayPatches(sidecar.Containers, pod.Spec.Containers, "/spec/containers")...)
-						patches = append(patches, createArrayPatches(sidecar.Volumes, pod.Spec.Volumes, "/spec/volumes")...)
-						patches = append(patches, createArrayPatches(sidecar.ImagePullSecrets, pod.Spec.ImagePullSecrets, "/spec/imagePullSecrets")...)
-						patches = append(patches, createObjectPatches(sidecar.Annotations, pod.Annotations, "/metadata/annotations", patcher.AllowAnnotationOverrides)...)
-						patches = append(patches, createObjectPatches(sidecar.Labels, pod.Labels, "/metadata/labels", patcher.AllowLabelOverrides)...)
-					}
-					log.Debugf("sidecar patches being applied for %v/%v: patches: %v", namespace, podName, patches)
-				}
+			reference, err := parseSidecarReference(configmapSidecarName)
+			if err != nil {
+				return nil, err
+			}
+			referenced, err := patcher.referencedSidecars(ctx, namespace, pod, reference, map[string]bool{})
+			if err != nil {
+				return nil, err
 			}
+			sidecars = append(sidecars, referenced...)
+		}
+		inlineSidecars, err := patcher.inlineSidecars(namespace, injection.ns, pod)
+		if err != nil {
+			return nil, err
+		}
+		for _, sidecar := range inlineSidecars {
+			sidecar.inline = true
+			sidecars = append(sidecars, sidecar)
+		}
+		for _, sidecar := range sidecars {
+			warnings := len(admission.Warnings(ctx))
+			err := patcher.injectSidecarInstances(ctx, injection, sidecar)
+			patcher.sidecarEvents(ctx, namespace, sidecar, warnings, err)
+			if err != nil {
+				return nil, err
+			}
+		}
+		status, err := patcher.checkBudgets(ctx, namespace, injection.ns, injection.sidecars)
+		if err != nil {
+			return nil, err
+		}
+		if container := injection.defaultApplicationContainer(); container != "" {
+			if status == nil {
+				status = map[string]string{}
+			}
+			status[defaultContainerAnnotation] = container
+		}
+		patches = append(injection.patches, patcher.injectionStatusPatches(injection.injected, injection.sidecars, status)...)
+		trace.SpanFromContext(ctx).SetAttributes(sidecarsKey.StringSlice(lo.Map(injection.sidecars, func(sidecar Sidecar, _ int) string {
+			return sidecar.Name
+		})))
+		for _, sidecar := range injection.sidecars {
+			sidecarInjections.WithLabelValues(patcher.metricLabel(MetricsLabelNamespace, namespace), patcher.metricLabel(MetricsLabelSidecar, sidecar.Name)).Inc()
+		}
+		if patches != nil && log.IsLevelEnabled(log.DebugLevel) {
+			admission.Logger(ctx).Debugf("sidecar patches being applied for %v/%v: patches: %v", namespace, podName, patcher.redactPatches(patches))
 		}
 	}
 	return patches, nil
 }
 
+// injectSidecarInstances Renders the sidecar with its parameters, then injects each of its instances
+func (patcher *SidecarInjectorPatcher) injectSidecarInstances(ctx context.Context, injection *podInjection, sidecar Sidecar) error {
+	params, err := patcher.sidecarParameters(injection.pod, sidecar)
+	if err != nil {
+		return fmt.Errorf("%s: %v", sidecarSource(injection.namespace, sidecar), err)
+	}
+	instances, err := expandSidecar(injection.pod, sidecar, params)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if err := patcher.injectSidecar(ctx, injection, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectSidecar Adapts the sidecar to the pod and policies, then creates the patches injecting it
+func (patcher *SidecarInjectorPatcher) injectSidecar(ctx context.Context, injection *podInjection, sidecar Sidecar) (err error) {
+	ctx, span := tracer.Start(ctx, "InjectSidecar", trace.WithAttributes(sidecarKey.String(sidecar.Name)))
+	defer func() { endSpan(span, err) }()
+	source := sidecarSource(injection.namespace, sidecar)
+	sidecar, matched, err := selectVariant(injection.pod, patcher.DefaultPlatform, sidecar)
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	if !matched {
+		os, arch := podPlatform(injection.pod, patcher.DefaultPlatform)
+		message := fmt.Sprintf("skipping %s without a variant for platform %s/%s", source, os, arch)
+		admission.Logger(ctx).Warn(message)
+		admission.AddWarning(ctx, message)
+		return nil
+	}
+	sidecar, err = patcher.applyImagePolicy(sidecar)
+	if err != nil {
+		return fmt.Errorf("%s rejected by image policy: %v", source, err)
+	}
+	sidecar, err = patcher.applyResourcePolicy(injection.pod, sidecar)
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	sidecar = patcher.applyLimitRanges(injection.containerLimits, sidecar)
+	sidecar, err = resolveContainerConflicts(injection.injected, sidecar)
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	sidecar, err = patcher.resolveNameCollisions(injection.injected, sidecar)
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	sidecar, err = applyBootstrap(injection.injected, sidecar)
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	sidecar, portEnv, err := patcher.assignAutoPorts(injection.injected, sidecar)
+	if err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	if conflicts := portConflicts(injection.injected, sidecar); len(conflicts) > 0 {
+		message := fmt.Sprintf("%s has conflicting ports: %s", source, strings.Join(conflicts, ", "))
+		if patcher.PortConflictAction == PortConflictActionDeny {
+			return errors.New(message)
+		}
+		admission.Logger(ctx).Warn(message)
+		admission.AddWarning(ctx, message)
+	}
+	if violations := podSecurityViolations(injection.podSecurity, injection.injected, sidecar); len(violations) > 0 {
+		message := fmt.Sprintf("%s violates PodSecurity %q: %s", source, injection.podSecurity.String(), strings.Join(violations, ", "))
+		if patcher.PodSecurityAction == PodSecurityActionDeny {
+			return errors.New(message)
+		}
+		message = "skipping " + message
+		admission.Logger(ctx).Warn(message)
+		admission.AddWarning(ctx, message)
+		return nil
+	}
+	if sidecar.inline {
+		if violations := inlineViolations(injection.injected, sidecar); len(violations) > 0 {
+			return fmt.Errorf("%s is not allowed to be privileged: %s", source, strings.Join(violations, ", "))
+		}
+	}
+	sidecar = applyShutdownOrder(ctx, source, sidecar)
+	sidecar = applyStartupHold(ctx, source, sidecar)
+	if sidecar.HoldApplicationUntilReady {
+		injection.patches = append(injection.patches, removeReplacedContainers(injection.injected, injection.held, sidecar)...)
+	}
+	appended, replacePatches := replaceContainerPatches(injection.injected, sidecar)
+	injection.patches = append(injection.patches, replacePatches...)
+	if sidecar.HoldApplicationUntilReady {
+		injection.patches = append(injection.patches, insertContainers(injection.injected, injection.held, appended.Containers)...)
+		injection.held += len(appended.Containers)
+		appended.Containers = nil
+	}
+	injection.patches = append(injection.patches, patcher.sidecarPatches(injection.injected, appended)...)
+	patcher.applySidecar(injection.injected, appended)
+	injection.patches = append(injection.patches, containerEnvPatches(injection.injected, portApplications(injection.pod, sidecar), portEnv)...)
+	injection.patches = append(injection.patches, bootstrapPatches(ctx, source, injection.injected, injection.pod.Spec.Containers, sidecar)...)
+	injection.patches = append(injection.patches, shutdownPatches(ctx, source, injection.injected, injection.pod.Spec.Containers, sidecar)...)
+	injection.sidecars = append(injection.sidecars, sidecar)
+	if sidecar.Revision != "" {
+		rolloutInjections.WithLabelValues(patcher.metricLabel(MetricsLabelSidecar, sidecar.Name), sidecar.Revision).Inc()
+	}
+	return nil
+}
+
+// sidecarSource Describes where a sidecar is defined for messages
+func sidecarSource(namespace string, sidecar Sidecar) string {
+	if sidecar.inline {
+		return fmt.Sprintf("inline sidecar %s", sidecar.Name)
+	}
+	return fmt.Sprintf("sidecar %s from configmap %s/%s", sidecar.Name, namespace, sidecar.configmap)
+}
+
+// referencedSidecars Resolves the sidecars to inject for a reference of the inject annotation
+func (patcher *SidecarInjectorPatcher) referencedSidecars(ctx context.Context, namespace string, pod corev1.Pod, reference sidecarReference, resolving map[string]bool) ([]Sidecar, error) {
+	if resolving[reference.Name] {
+		return nil, fmt.Errorf("sidecar configmap %s/%s is part of a replacement cycle", namespace, reference.Name)
+	}
+	resolving[reference.Name] = true
+	defer delete(resolving, reference.Name)
+	var sidecars, selected []Sidecar
+	for _, versions := range groupSidecarVersions(patcher.configmapSidecars(ctx, namespace, reference.Name)) {
+		sidecar, err := selectSidecarVersion(versions, reference.Version)
+		if err != nil {
+			message := fmt.Sprintf("skipping sidecar from configmap %s/%s - %v", namespace, reference.Name, err)
+			admission.Logger(ctx).Error(message)
+			admission.AddWarning(ctx, message)
+			patcher.configmapEvent(namespace, reference.Name, eventReasonInjectionWarning, message)
+			continue
+		}
+		sidecar.configmap = reference.Name
+		sidecar.params = reference.Params
+		sidecar = selectSidecarRevision(namespace, pod, sidecar)
+		selected = append(selected, sidecar)
+		warnings := len(admission.Warnings(ctx))
+		replacement, err := patcher.checkDeprecation(ctx, sidecar)
+		patcher.sidecarEvents(ctx, namespace, sidecar, warnings, err)
+		if err != nil {
+			return nil, err
+		}
+		if replacement != nil {
+			replacementSidecars, err := patcher.referencedSidecars(ctx, namespace, pod, *replacement, resolving)
+			if err != nil {
+				return nil, err
+			}
+			sidecars = append(sidecars, replacementSidecars...)
+			continue
+		}
+		sidecars = append(sidecars, sidecar)
+	}
+	if name, undeclared := undeclaredParameter(reference, selected); undeclared && len(selected) > 0 {
+		err := fmt.Errorf("sidecar configmap %s/%s: unknown parameter %s", namespace, reference.Name, name)
+		patcher.configmapEvent(namespace, reference.Name, eventReasonInjectionFailed, fmt.Sprintf("pod denied: %v", err))
+		return nil, err
+	}
+	return sidecars, nil
+}
+
+// injectionStatusPatches Records the status annotations along with the versions and revisions of the injected sidecars on the pod
+func (patcher *SidecarInjectorPatcher) injectionStatusPatches(pod *corev1.Pod, sidecars []Sidecar, status map[string]string) []admission.PatchOperation {
+	if status == nil {
+		status = map[string]string{}
+	}
+	if versions := injectedVersions(sidecars); versions != "" {
+		status[patcher.injectedVersionsAnnotation()] = versions
+	}
+	if revisions := injectedRevisions(sidecars); revisions != "" {
+		status[patcher.injectedRevisionsAnnotation()] = revisions
+	}
+	return createObjectPatches(status, pod.Annotations, "/metadata/annotations", true)
+}
+
+// namespaceRequired Whether a feature enabled for the pod reads its namespace, which is not fetched otherwise
+func (patcher *SidecarInjectorPatcher) namespaceRequired(pod corev1.Pod) bool {
+	_, inline := pod.Annotations[patcher.inlineSidecarsAnnotation()]
+	podSecurity := patcher.PodSecurityAction != "" && patcher.PodSecurityAction != PodSecurityActionNone
+	return inline || podSecurity || patcher.podBudgetsEnabled() || patcher.NamespaceBudgetsEnabled()
+}
+
+// fetchNamespace Fetches the namespace of the pod, nil when it could not be fetched
+func (patcher *SidecarInjectorPatcher) fetchNamespace(ctx context.Context, namespace string) *corev1.Namespace {
+	ns, err := patcher.K8sClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		admission.Logger(ctx).Warnf("namespace %s was not found", namespace)
+		return nil
+	} else if err != nil {
+		admission.Logger(ctx).Errorf("error fetching namespace %s - %v", namespace, err)
+		return nil
+	}
+	return ns
+}
+
+// configmapSidecars Fetches and parses the sidecars defined in a ConfigMap, logging any failure
+func (patcher *SidecarInjectorPatcher) configmapSidecars(ctx context.Context, namespace string, configmapSidecarName string) []Sidecar {
+	ctx, span := tracer.Start(ctx, "GetSidecarConfigMap", trace.WithAttributes(semconv.K8SNamespaceName(namespace), sidecarKey.String(configmapSidecarName)))
+	defer span.End()
+	configmapSidecar, err := patcher.K8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, configmapSidecarName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		patcher.recordConfigMapLookup(namespace, configmapSidecarName, configmapLookupNotFound, 0)
+		message := fmt.Sprintf("sidecar configmap %s/%s was not found", namespace, configmapSidecarName)
+		admission.Logger(ctx).Warn(message)
+		admission.AddWarning(ctx, message)
+		patcher.configmapEvent(namespace, configmapSidecarName, eventReasonConfigMapNotFound, fmt.Sprintf("sidecar %s referenced by a pod was not found", configmapSidecarName))
+		return nil
+	} else if err != nil {
+		message := fmt.Sprintf("error fetching sidecar configmap %s/%s - %v", namespace, configmapSidecarName, err)
+		admission.Logger(ctx).Error(message)
+		admission.AddWarning(ctx, message)
+		patcher.recordConfigMapLookup(namespace, configmapSidecarName, configmapLookupError, 0)
+		patcher.configmapEvent(namespace, configmapSidecarName, eventReasonConfigMapLookupFailed, fmt.Sprintf("error fetching sidecar %s - %v", configmapSidecarName, err))
+		return nil
+	}
+	sidecars, err := patcher.configmapDefinitions(configmapSidecar)
+	if err != nil {
+		admission.Logger(ctx).Errorf("error unmarshalling %s from configmap %s/%s - %v", patcher.SidecarDataKey, namespace, configmapSidecarName, err)
+		patcher.recordConfigMapLookup(namespace, configmapSidecarName, configmapLookupParseError, 0)
+		admission.AddWarning(ctx, fmt.Sprintf("skipping sidecar configmap %s/%s with invalid definitions", namespace, configmapSidecarName))
+		patcher.configmapEvent(namespace, configmapSidecarName, eventReasonConfigMapInvalid, fmt.Sprintf("error unmarshalling sidecars - %v", err))
+		return nil
+	}
+	patcher.recordConfigMapLookup(namespace, configmapSidecarName, configmapLookupFound, len(sidecars))
+	var extended []Sidecar
+	for _, sidecar := range sidecars {
+		sidecar, err := patcher.extendedSidecar(ctx, namespace, configmapSidecarName, sidecar, nil)
+		if err != nil {
+			message := fmt.Sprintf("skipping sidecar %s from configmap %s/%s extending %s - %v", sidecar.Name, namespace, configmapSidecarName, sidecar.Extends, err)
+			admission.Logger(ctx).Error(message)
+			admission.AddWarning(ctx, message)
+			patcher.configmapEvent(namespace, configmapSidecarName, eventReasonConfigMapInvalid, fmt.Sprintf("sidecar %s cannot extend %s - %v", sidecar.Name, sidecar.Extends, err))
+			continue
+		}
+		extended = append(extended, sidecar)
+	}
+	return extended
+}
+
+// sidecarPatches Creates the patches injecting the sidecar into the pod
+func (patcher *SidecarInjectorPatcher) sidecarPatches(pod *corev1.Pod, sidecar Sidecar) []admission.PatchOperation {
+	var patches []admission.PatchOperation
+	patches = append(patches, createArrayPatches(sidecar.InitContainers, pod.Spec.InitContainers, "/spec/initContainers")...)
+	patches = append(patches, createArrayPatches(sidecar.Containers, pod.Spec.Containers, "/spec/containers")...)
+	patches = append(patches, createArrayPatches(sidecar.Volumes, pod.Spec.Volumes, "/spec/volumes")...)
+	patches = append(patches, createArrayPatches(sidecar.ImagePullSecrets, pod.Spec.ImagePullSecrets, "/spec/imagePullSecrets")...)
+	patches = append(patches, createObjectPatches(sidecar.Annotations, pod.Annotations, "/metadata/annotations", patcher.AllowAnnotationOverrides)...)
+	patches = append(patches, createObjectPatches(sidecar.Labels, pod.Labels, "/metadata/labels", patcher.AllowLabelOverrides)...)
+	return patches
+}
+
+// applySidecar Applies the sidecar to the pod the same way its patches would
+func (patcher *SidecarInjectorPatcher) applySidecar(pod *corev1.Pod, sidecar Sidecar) {
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, sidecar.InitContainers...)
+	pod.Spec.Containers = append(pod.Spec.Containers, sidecar.Containers...)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, sidecar.Volumes...)
+	pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, sidecar.ImagePullSecrets...)
+	pod.Annotations = mergeObject(sidecar.Annotations, pod.Annotations, patcher.AllowAnnotationOverrides)
+	pod.Labels = mergeObject(sidecar.Labels, pod.Labels, patcher.AllowLabelOverrides)
+}
+
+// containerIndex Index of the named container, -1 when missing
+func containerIndex(containers []corev1.Container, name string) int {
+	for index, container := range containers {
+		if container.Name == name {
+			return index
+		}
+	}
+	return -1
+}
+
+// portApplications Application containers the auto ports of the sidecar are exposed to, only the container a per
+// container instance was expanded for
+func portApplications(pod corev1.Pod, sidecar Sidecar) []corev1.Container {
+	if sidecar.application == "" {
+		return pod.Spec.Containers
+	}
+	return lo.Filter(pod.Spec.Containers, func(container corev1.Container, _ int) bool {
+		return container.Name == sidecar.application
+	})
+}
+
+// containerEnvPatches Adds the environment variables to the application containers of the pod
+func containerEnvPatches(pod *corev1.Pod, applications []corev1.Container, env []corev1.EnvVar) []admission.PatchOperation {
+	if len(env) == 0 {
+		return nil
+	}
+	var patches []admission.PatchOperation
+	for _, application := range applications {
+		index := containerIndex(pod.Spec.Containers, application.Name)
+		if index < 0 {
+			continue
+		}
+		patches = append(patches, createArrayPatches(env, pod.Spec.Containers[index].Env, fmt.Sprintf("/spec/containers/%d/env", index))...)
+		pod.Spec.Containers[index].Env = append(pod.Spec.Containers[index].Env, env...)
+	}
+	return patches
+}
+
+func mergeObject(newMap map[string]string, existingMap map[string]string, override bool) map[string]string {
+	if len(newMap) == 0 {
+		return existingMap
+	}
+	merged := make(map[string]string, len(existingMap)+len(newMap))
+	for key, value := range existingMap {
+		merged[key] = value
+	}
+	for key, value := range newMap {
+		if _, ok := merged[key]; !ok || override {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
 /*PatchPodUpdate not supported, only support create */
 func (patcher *SidecarInjectorPatcher) PatchPodUpdate(_ context.Context, _ string, _ corev1.Pod, _ corev1.Pod) ([]admission.PatchOperation, error) {
 	return nil, nil
@@ -199,6 +199,73 @@ func TestSidecarInjectorPatcher_PatchPodCreate(t *testing.T) {
 	}
 }
 
+func TestSidecarInjectorPatcher_PatchPodCreateNamespaceFetch(t *testing.T) {
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test",
+		Annotations: map[string]string{"sidecar-injector.expedia.com/allow-inline-sidecars": "true"},
+	}}
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "logs", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: logs
+                       containers:
+                         - name: logs`,
+		},
+	}
+	tests := []struct {
+		name        string
+		patcher     SidecarInjectorPatcher
+		annotations map[string]string
+		want        bool
+	}{
+		{
+			name:        "default configuration",
+			annotations: map[string]string{"sidecar-injector.expedia.com/inject": "logs"},
+			want:        false,
+		},
+		{
+			name:        "pod security",
+			patcher:     SidecarInjectorPatcher{PodSecurityAction: PodSecurityActionDeny},
+			annotations: map[string]string{"sidecar-injector.expedia.com/inject": "logs"},
+			want:        true,
+		},
+		{
+			name:        "pod budgets",
+			patcher:     SidecarInjectorPatcher{PodBudgets: true},
+			annotations: map[string]string{"sidecar-injector.expedia.com/inject": "logs"},
+			want:        true,
+		},
+		{
+			name:        "namespace budget",
+			patcher:     SidecarInjectorPatcher{NamespaceBudget: ResourceBudget{MaxSidecars: 5}},
+			annotations: map[string]string{"sidecar-injector.expedia.com/inject": "logs"},
+			want:        true,
+		},
+		{
+			name:        "inline sidecars",
+			annotations: map[string]string{"sidecar-injector.expedia.com/inline-sidecars": "name: inline"},
+			want:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(namespace, configmap)
+			patcher := tt.patcher
+			patcher.K8sClient = client
+			patcher.InjectPrefix = "sidecar-injector.expedia.com"
+			patcher.InjectName = "inject"
+			patcher.SidecarDataKey = "sidecars.yaml"
+			_, err := patcher.PatchPodCreate(context.Background(), "test", v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}})
+			assert.NoError(t, err)
+			fetched := false
+			for _, action := range client.Actions() {
+				fetched = fetched || (action.GetVerb() == "get" && action.GetResource().Resource == "namespaces")
+			}
+			assert.Equalf(t, tt.want, fetched, "namespace fetched")
+		})
+	}
+}
+
 func TestSidecarInjectorPatcher_PatchPodDelete(t *testing.T) {
 	type fields struct {
 		K8sClient                kubernetes.Interface
@@ -395,7 +462,7 @@ func TestSidecarInjectorPatcher_configmapSidecarNames(t *testing.T) {
 				AllowAnnotationOverrides: tt.fields.AllowAnnotationOverrides,
 				AllowLabelOverrides:      tt.fields.AllowLabelOverrides,
 			}
-			got := patcher.configmapSidecarNames(tt.args.namespace, tt.args.pod)
+			got := patcher.configmapSidecarNames(context.Background(), tt.args.namespace, tt.args.pod)
 			assert.Equalf(t, tt.want, got, "configmapSidecarNames(%v, %v)", tt.args.namespace, tt.args.pod)
 		})
 	}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// defaultReadinessTimeoutSeconds Time the application is held for a sidecar container to become ready
+const defaultReadinessTimeoutSeconds = 120
+
+// SidecarReadinessHook Check run by the postStart hook of containers holding the application until they are ready
+type SidecarReadinessHook struct {
+	Command        []string `yaml:"command"`
+	TimeoutSeconds int      `yaml:"timeoutSeconds"`
+}
+
+// readinessCheck Shell command succeeding once the container is ready, from the hook command or the readiness probe
+func readinessCheck(container corev1.Container, hook *SidecarReadinessHook) (string, error) {
+	if hook != nil && len(hook.Command) > 0 {
+		return shellJoin(hook.Command), nil
+	}
+	probe := container.ReadinessProbe
+	switch {
+	case probe == nil:
+		return "", fmt.Errorf("no readiness probe or readiness hook command")
+	case probe.Exec != nil:
+		return shellJoin(probe.Exec.Command), nil
+	case probe.HTTPGet != nil:
+		port, err := probePort(container, probe.HTTPGet.Port)
+		if err != nil {
+			return "", err
+		}
+		host := probe.HTTPGet.Host
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		scheme := strings.ToLower(string(probe.HTTPGet.Scheme))
+		if scheme == "" {
+			scheme = "http"
+		}
+		path := probe.HTTPGet.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return shellJoin([]string{"wget", "-q", "-O", "/dev/null", fmt.Sprintf("%s://%s:%d%s", scheme, host, port, path)}), nil
+	case probe.TCPSocket != nil:
+		port, err := probePort(container, probe.TCPSocket.Port)
+		if err != nil {
+			return "", err
+		}
+		host := probe.TCPSocket.Host
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		return shellJoin([]string{"nc", "-z", host, fmt.Sprint(port)}), nil
+	default:
+		return "", fmt.Errorf("readiness probe cannot be checked from a hook")
+	}
+}
+
+// probePort Number of a probe port, resolving named container ports
+func probePort(container corev1.Container, port intstr.IntOrString) (int32, error) {
+	if port.Type == intstr.Int {
+		return port.IntVal, nil
+	}
+	for _, containerPort := range container.Ports {
+		if containerPort.Name == port.StrVal {
+			return containerPort.ContainerPort, nil
+		}
+	}
+	return 0, fmt.Errorf("readiness probe port %s is not a port of the container", port.StrVal)
+}
+
+// readinessScript Shell script waiting for the check to succeed
+func readinessScript(check string, timeoutSeconds int) string {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultReadinessTimeoutSeconds
+	}
+	return fmt.Sprintf("i=0; until %s; do i=$((i+1)); if [ $i -ge %d ]; then exit 1; fi; sleep 1; done", check, timeoutSeconds)
+}
+
+// applyStartupHold Adds a postStart hook waiting for readiness to the containers of a sidecar holding the application
+func applyStartupHold(ctx context.Context, source string, sidecar Sidecar) Sidecar {
+	if !sidecar.HoldApplicationUntilReady {
+		return sidecar
+	}
+	timeoutSeconds := 0
+	if sidecar.ReadinessHook != nil {
+		timeoutSeconds = sidecar.ReadinessHook.TimeoutSeconds
+	}
+	containers := make([]corev1.Container, 0, len(sidecar.Containers))
+	for _, container := range sidecar.Containers {
+		check, err := readinessCheck(container, sidecar.ReadinessHook)
+		if err == nil {
+			container, err = withPostStart(container, readinessScript(check, timeoutSeconds))
+		}
+		if err != nil {
+			warnLifecycle(ctx, fmt.Sprintf("%s cannot hold the application until container %s is ready: %v", source, container.Name, err))
+		}
+		containers = append(containers, container)
+	}
+	sidecar.Containers = containers
+	return sidecar
+}
+
+// withPostStart Copy of the container with the script wrapped around its postStart hook
+func withPostStart(container corev1.Container, script string) (corev1.Container, error) {
+	lifecycle := corev1.Lifecycle{}
+	if container.Lifecycle != nil {
+		lifecycle = *container.Lifecycle
+	}
+	postStart, err := wrapHook(lifecycle.PostStart, script)
+	if err != nil {
+		return container, err
+	}
+	lifecycle.PostStart = postStart
+	container.Lifecycle = &lifecycle
+	return container, nil
+}
+
+// insertContainers Inserts the containers at the index of the pod containers, so the kubelet starts them first
+func insertContainers(pod *corev1.Pod, index int, containers []corev1.Container) []admission.PatchOperation {
+	var patches []admission.PatchOperation
+	for offset, container := range containers {
+		patches = append(patches, admission.PatchOperation{Op: "add", Path: fmt.Sprintf("/spec/containers/%d", index+offset), Value: container})
+	}
+	pod.Spec.Containers = slices.Insert(pod.Spec.Containers, index, containers...)
+	return patches
+}
+
+// removeReplacedContainers Removes the application containers replaced by the containers of a sidecar holding the
+// application, so that the replacements are inserted before the application containers instead of taking their place
+func removeReplacedContainers(pod *corev1.Pod, held int, sidecar Sidecar) []admission.PatchOperation {
+	var patches []admission.PatchOperation
+	for _, container := range sidecar.Containers {
+		index := containerIndex(pod.Spec.Containers, container.Name)
+		if !sidecar.replaces[container.Name] || index < held {
+			continue
+		}
+		patches = append(patches, admission.PatchOperation{Op: "remove", Path: fmt.Sprintf("/spec/containers/%d", index)})
+		pod.Spec.Containers = slices.Delete(pod.Spec.Containers, index, index+1)
+	}
+	return patches
+}
+
+// defaultApplicationContainer First application container, set as the default container of kubectl when sidecars are
+// inserted before it and the pod does not name its default container
+func (injection *podInjection) defaultApplicationContainer() string {
+	if injection.held == 0 || injection.held >= len(injection.injected.Spec.Containers) {
+		return ""
+	}
+	if _, ok := injection.injected.Annotations[defaultContainerAnnotation]; ok {
+		return ""
+	}
+	return injection.injected.Spec.Containers[injection.held].Name
+}
@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_readinessCheck(t *testing.T) {
+	ports := []v1.ContainerPort{{Name: "status", ContainerPort: 15021}}
+	tests := []struct {
+		name    string
+		probe   *v1.Probe
+		hook    *SidecarReadinessHook
+		want    string
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "hook command",
+			probe:   &v1.Probe{ProbeHandler: v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{Port: intstr.FromInt(15021)}}},
+			hook:    &SidecarReadinessHook{Command: []string{"/ready"}},
+			want:    "'/ready'",
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "http probe with named port",
+			probe:   &v1.Probe{ProbeHandler: v1.ProbeHandler{HTTPGet: &v1.HTTPGetAction{Path: "/healthz/ready", Port: intstr.FromString("status")}}},
+			want:    "'wget' '-q' '-O' '/dev/null' 'http://127.0.0.1:15021/healthz/ready'",
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "tcp probe",
+			probe:   &v1.Probe{ProbeHandler: v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{Port: intstr.FromInt(15021)}}},
+			want:    "'nc' '-z' '127.0.0.1' '15021'",
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "exec probe",
+			probe:   &v1.Probe{ProbeHandler: v1.ProbeHandler{Exec: &v1.ExecAction{Command: []string{"cat", "/tmp/ready"}}}},
+			want:    "'cat' '/tmp/ready'",
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "unknown named port",
+			probe:   &v1.Probe{ProbeHandler: v1.ProbeHandler{HTTPGet: &v1.HTTPGetAction{Port: intstr.FromString("admin")}}},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "no probe",
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readinessCheck(v1.Container{Name: "envoy", Ports: ports, ReadinessProbe: tt.probe}, tt.hook)
+			if !tt.wantErr(t, err, "readinessCheck(%v)", tt.probe) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.want, got, "readinessCheck(%v)", tt.probe)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateHold(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "proxies", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: envoy
+                       holdApplicationUntilReady: true
+                       readinessHook:
+                         command: [ "/ready" ]
+                         timeoutSeconds: 30
+                       containers:
+                         - name: envoy
+                     - name: vault
+                       holdApplicationUntilReady: true
+                       containers:
+                         - name: vault
+                           readinessProbe:
+                             tcpSocket:
+                               port: 8200
+                     - name: logs
+                       containers:
+                         - name: logs`,
+		},
+	}
+	fluentd := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "fluentd", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: fluentd
+                       conflictStrategy: replace
+                       holdApplicationUntilReady: true
+                       readinessHook:
+                         command: [ "/ready" ]
+                       containers:
+                         - name: fluentd
+                           image: fluentd:2.0`,
+		},
+	}
+	envoy := v1.Container{Name: "envoy", Lifecycle: &v1.Lifecycle{
+		PostStart: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c",
+			"i=0; until '/ready'; do i=$((i+1)); if [ $i -ge 30 ]; then exit 1; fi; sleep 1; done"}}},
+	}}
+	vault := v1.Container{
+		Name:           "vault",
+		ReadinessProbe: &v1.Probe{ProbeHandler: v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{Port: intstr.FromInt(8200)}}},
+		Lifecycle: &v1.Lifecycle{PostStart: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c",
+			"i=0; until 'nc' '-z' '127.0.0.1' '8200'; do i=$((i+1)); if [ $i -ge 120 ]; then exit 1; fi; sleep 1; done"}}}},
+	}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		containers  []v1.Container
+		want        []admission.PatchOperation
+	}{
+		{
+			name:        "held sidecars",
+			annotations: map[string]string{"sidecar-injector.expedia.com/inject": "proxies"},
+			containers:  []v1.Container{{Name: "app"}},
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/0", Value: envoy},
+				{Op: "add", Path: "/spec/containers/1", Value: vault},
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "logs"}},
+				{Op: "add", Path: "/metadata/annotations/kubectl.kubernetes.io~1default-container", Value: "app"},
+			},
+		},
+		{
+			name:        "default container kept",
+			annotations: map[string]string{"sidecar-injector.expedia.com/inject": "proxies", "kubectl.kubernetes.io/default-container": "worker"},
+			containers:  []v1.Container{{Name: "app"}, {Name: "worker"}},
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/spec/containers/0", Value: envoy},
+				{Op: "add", Path: "/spec/containers/1", Value: vault},
+				{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "logs"}},
+			},
+		},
+		{
+			name:        "replaced container moved before the application",
+			annotations: map[string]string{"sidecar-injector.expedia.com/inject": "fluentd"},
+			containers:  []v1.Container{{Name: "app"}, {Name: "fluentd", Image: "fluentd:1.0"}},
+			want: []admission.PatchOperation{
+				{Op: "remove", Path: "/spec/containers/1"},
+				{Op: "add", Path: "/spec/containers/0", Value: v1.Container{Name: "fluentd", Image: "fluentd:2.0", Lifecycle: &v1.Lifecycle{
+					PostStart: &v1.LifecycleHandler{Exec: &v1.ExecAction{Command: []string{"/bin/sh", "-c",
+						"i=0; until '/ready'; do i=$((i+1)); if [ $i -ge 120 ]; then exit 1; fi; sleep 1; done"}}},
+				}}},
+				{Op: "add", Path: "/metadata/annotations/kubectl.kubernetes.io~1default-container", Value: "app"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:      fake.NewSimpleClientset(configmap, fluentd),
+				InjectPrefix:   "sidecar-injector.expedia.com",
+				InjectName:     "inject",
+				SidecarDataKey: "sidecars.yaml",
+			}
+			pod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       v1.PodSpec{Containers: tt.containers},
+			}
+			got, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
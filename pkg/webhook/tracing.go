@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/expediagroup/kubernetes-sidecar-injector/pkg/webhook")
+
+const (
+	// sidecarKey Span attribute of the name of a sidecar or its ConfigMap
+	sidecarKey = attribute.Key("sidecar_injector.sidecar")
+	// sidecarsKey Span attribute of the names of the sidecars injected into a pod
+	sidecarsKey = attribute.Key("sidecar_injector.sidecars")
+)
+
+// endSpan Ends the span, recording the error failing it if any
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
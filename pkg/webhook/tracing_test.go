@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSidecarInjectorPatcher_PatchPodCreateSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(provider)
+
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "envoy", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: envoy
+                       containers:
+                         - name: envoy`,
+		},
+	}
+	patcher := &SidecarInjectorPatcher{
+		K8sClient:      fake.NewSimpleClientset(configmap),
+		InjectPrefix:   "sidecar-injector.expedia.com",
+		InjectName:     "inject",
+		SidecarDataKey: "sidecars.yaml",
+	}
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		GenerateName: "app-",
+		Annotations:  map[string]string{"sidecar-injector.expedia.com/inject": "envoy, missing"},
+	}}
+	_, err := patcher.PatchPodCreate(context.Background(), "test", pod)
+	assert.NoError(t, err)
+
+	type span struct {
+		name       string
+		attributes []attribute.KeyValue
+		status     codes.Code
+	}
+	var got []span
+	for _, ended := range recorder.Ended() {
+		got = append(got, span{name: ended.Name(), attributes: ended.Attributes(), status: ended.Status().Code})
+	}
+	assert.Equal(t, []span{
+		{name: "GetSidecarConfigMap", attributes: []attribute.KeyValue{attribute.String("k8s.namespace.name", "test"), attribute.String("sidecar_injector.sidecar", "envoy")}},
+		{name: "GetSidecarConfigMap", attributes: []attribute.KeyValue{attribute.String("k8s.namespace.name", "test"), attribute.String("sidecar_injector.sidecar", "missing")}},
+		{name: "InjectSidecar", attributes: []attribute.KeyValue{attribute.String("sidecar_injector.sidecar", "envoy")}},
+		{name: "PatchPodCreate", attributes: []attribute.KeyValue{
+			attribute.String("k8s.namespace.name", "test"),
+			attribute.String("k8s.pod.name", "app-"),
+			attribute.StringSlice("sidecar_injector.sidecars", []string{"envoy"}),
+		}},
+	}, got)
+}
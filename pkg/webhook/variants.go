@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SidecarVariant Container overrides of a sidecar for pods running on an operating system and architecture
+type SidecarVariant struct {
+	OS         string                     `yaml:"os"`
+	Arch       string                     `yaml:"arch"`
+	Containers []SidecarContainerOverride `yaml:"containers"`
+}
+
+// SidecarContainerOverride Fields of a sidecar container or init container overridden by a variant
+type SidecarContainerOverride struct {
+	Name            string                  `yaml:"name"`
+	Image           string                  `yaml:"image"`
+	Command         []string                `yaml:"command"`
+	SecurityContext *corev1.SecurityContext `yaml:"securityContext"`
+}
+
+// podPlatform Operating system and architecture of the pod, from its os, node selector or required node affinity,
+// falling back to the default platform
+func podPlatform(pod corev1.Pod, defaultPlatform string) (string, string) {
+	os, arch, _ := strings.Cut(defaultPlatform, "/")
+	if value := nodeLabelValue(pod, corev1.LabelOSStable); value != "" {
+		os = value
+	}
+	if pod.Spec.OS != nil && pod.Spec.OS.Name != "" {
+		os = string(pod.Spec.OS.Name)
+	}
+	if value := nodeLabelValue(pod, corev1.LabelArchStable); value != "" {
+		arch = value
+	}
+	return os, arch
+}
+
+// nodeLabelValue Single value of a node label the pod is restricted to, empty when unrestricted or ambiguous
+func nodeLabelValue(pod corev1.Pod, label string) string {
+	if value, ok := pod.Spec.NodeSelector[label]; ok {
+		return value
+	}
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil || pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	value := ""
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		termValue := ""
+		for _, expression := range term.MatchExpressions {
+			if expression.Key == label && expression.Operator == corev1.NodeSelectorOpIn && len(expression.Values) == 1 {
+				termValue = expression.Values[0]
+			}
+		}
+		if termValue == "" || (value != "" && value != termValue) {
+			return ""
+		}
+		value = termValue
+	}
+	return value
+}
+
+// selectVariant Applies the first variant matching the platform of the pod, false when the sidecar has variants but
+// none of them match
+func selectVariant(pod corev1.Pod, defaultPlatform string, sidecar Sidecar) (Sidecar, bool, error) {
+	if len(sidecar.Variants) == 0 {
+		return sidecar, true, nil
+	}
+	os, arch := podPlatform(pod, defaultPlatform)
+	for _, variant := range sidecar.Variants {
+		if (variant.OS == "" || variant.OS == os) && (variant.Arch == "" || variant.Arch == arch) {
+			sidecar, err := applyVariant(sidecar, variant)
+			return sidecar, true, err
+		}
+	}
+	return sidecar, false, nil
+}
+
+func applyVariant(sidecar Sidecar, variant SidecarVariant) (Sidecar, error) {
+	sidecar.InitContainers = append([]corev1.Container(nil), sidecar.InitContainers...)
+	sidecar.Containers = append([]corev1.Container(nil), sidecar.Containers...)
+	for _, override := range variant.Containers {
+		container := overriddenContainer(sidecar, override.Name)
+		if container == nil {
+			return sidecar, fmt.Errorf("variant overrides unknown container %s", override.Name)
+		}
+		if override.Image != "" {
+			container.Image = override.Image
+		}
+		if override.Command != nil {
+			container.Command = override.Command
+		}
+		if override.SecurityContext != nil {
+			container.SecurityContext = override.SecurityContext
+		}
+	}
+	sidecar.Variants = nil
+	return sidecar, nil
+}
+
+func overriddenContainer(sidecar Sidecar, name string) *corev1.Container {
+	if index := containerIndex(sidecar.InitContainers, name); index >= 0 {
+		return &sidecar.InitContainers[index]
+	}
+	if index := containerIndex(sidecar.Containers, name); index >= 0 {
+		return &sidecar.Containers[index]
+	}
+	return nil
+}
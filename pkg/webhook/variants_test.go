@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_podPlatform(t *testing.T) {
+	archAffinity := func(values ...[]string) *v1.Affinity {
+		var terms []v1.NodeSelectorTerm
+		for _, termValues := range values {
+			terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+				{Key: "kubernetes.io/arch", Operator: v1.NodeSelectorOpIn, Values: termValues},
+			}})
+		}
+		return &v1.Affinity{NodeAffinity: &v1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: terms}}}
+	}
+	tests := []struct {
+		name     string
+		spec     v1.PodSpec
+		wantOS   string
+		wantArch string
+	}{
+		{name: "default", wantOS: "linux", wantArch: "amd64"},
+		{name: "pod os", spec: v1.PodSpec{OS: &v1.PodOS{Name: v1.Windows}}, wantOS: "windows", wantArch: "amd64"},
+		{name: "node selector", spec: v1.PodSpec{NodeSelector: map[string]string{"kubernetes.io/os": "windows", "kubernetes.io/arch": "arm64"}}, wantOS: "windows", wantArch: "arm64"},
+		{name: "affinity", spec: v1.PodSpec{Affinity: archAffinity([]string{"arm64"}, []string{"arm64"})}, wantOS: "linux", wantArch: "arm64"},
+		{name: "ambiguous affinity", spec: v1.PodSpec{Affinity: archAffinity([]string{"arm64", "amd64"})}, wantOS: "linux", wantArch: "amd64"},
+		{name: "differing affinity terms", spec: v1.PodSpec{Affinity: archAffinity([]string{"arm64"}, []string{"s390x"})}, wantOS: "linux", wantArch: "amd64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os, arch := podPlatform(v1.Pod{Spec: tt.spec}, "linux/amd64")
+			assert.Equal(t, tt.wantOS, os)
+			assert.Equal(t, tt.wantArch, arch)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateVariants(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: agent
+                       containers:
+                         - name: agent
+                           image: agent:amd64
+                       variants:
+                         - os: linux
+                           arch: amd64
+                         - os: linux
+                           arch: arm64
+                           containers:
+                             - name: agent
+                               image: agent:arm64
+                               command: [ agent-arm64 ]`,
+		},
+	}
+	tests := []struct {
+		name         string
+		nodeSelector map[string]string
+		want         []admission.PatchOperation
+		wantWarnings []string
+	}{
+		{
+			name: "default platform",
+			want: []admission.PatchOperation{{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "agent", Image: "agent:amd64"}}},
+		},
+		{
+			name:         "arm64 variant",
+			nodeSelector: map[string]string{"kubernetes.io/arch": "arm64"},
+			want:         []admission.PatchOperation{{Op: "add", Path: "/spec/containers/-", Value: v1.Container{Name: "agent", Image: "agent:arm64", Command: []string{"agent-arm64"}}}},
+		},
+		{
+			name:         "no variant",
+			nodeSelector: map[string]string{"kubernetes.io/os": "windows"},
+			wantWarnings: []string{"skipping sidecar agent from configmap test/agent without a variant for platform windows/amd64"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"sidecar-injector.expedia.com/inject": "agent"},
+				},
+				Spec: v1.PodSpec{NodeSelector: tt.nodeSelector, Containers: []v1.Container{{Name: "app"}}},
+			}
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:       fake.NewSimpleClientset(configmap),
+				InjectPrefix:    "sidecar-injector.expedia.com",
+				InjectName:      "inject",
+				SidecarDataKey:  "sidecars.yaml",
+				DefaultPlatform: "linux/amd64",
+			}
+			ctx := admission.WithWarnings(context.Background())
+			got, err := patcher.PatchPodCreate(ctx, "test", pod)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantWarnings, admission.Warnings(ctx))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// sidecarReference A sidecar ConfigMap referenced by the inject annotation, e.g. `fluent-bit@^1.2`
+type sidecarReference struct {
+	Name    string
+	Version string
+	Params  map[string]string
+}
+
+func (ref sidecarReference) String() string {
+	if ref.Version == "" {
+		return ref.Name
+	}
+	return ref.Name + "@" + ref.Version
+}
+
+// parseSidecarReference Splits a reference into the ConfigMap name, the optional version or semver range and the
+// optional parameter list, e.g. `otel-agent@^1.0(samplingRate=0.1)`
+func parseSidecarReference(reference string) (sidecarReference, error) {
+	var params map[string]string
+	if open := strings.Index(reference, "("); open >= 0 {
+		closing := strings.LastIndex(reference, ")")
+		if closing < open || strings.TrimSpace(reference[closing+1:]) != "" {
+			return sidecarReference{}, fmt.Errorf("sidecar reference %q has an unterminated parameter list", reference)
+		}
+		var err error
+		if params, err = parseParameters(reference[open+1 : closing]); err != nil {
+			return sidecarReference{}, fmt.Errorf("sidecar reference %q: %v", reference, err)
+		}
+		reference = reference[:open]
+	}
+	name, version, _ := strings.Cut(reference, "@")
+	name = strings.TrimSpace(name)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return sidecarReference{}, fmt.Errorf("sidecar reference %q: %s, the constraints of a version range are separated by spaces or commas", reference, strings.Join(errs, ", "))
+	}
+	return sidecarReference{Name: name, Version: strings.TrimSpace(version), Params: params}, nil
+}
+
+// groupSidecarVersions Groups the versioned sidecar definitions by name, keeping the order the names first appear in.
+// Unversioned sidecars are not grouped and always injected.
+func groupSidecarVersions(sidecars []Sidecar) [][]Sidecar {
+	var groups [][]Sidecar
+	indexes := map[string]int{}
+	for _, sidecar := range sidecars {
+		if sidecar.Version == "" {
+			groups = append(groups, []Sidecar{sidecar})
+			continue
+		}
+		if index, ok := indexes[sidecar.Name]; ok {
+			groups[index] = append(groups[index], sidecar)
+			continue
+		}
+		indexes[sidecar.Name] = len(groups)
+		groups = append(groups, []Sidecar{sidecar})
+	}
+	return groups
+}
+
+// selectSidecarVersion Selects the highest version matching the constraint, or the default version without constraint.
+// An unversioned sidecar is always selected.
+func selectSidecarVersion(versions []Sidecar, constraint string) (Sidecar, error) {
+	if versions[0].Version == "" {
+		return versions[0], nil
+	}
+	name := versions[0].Name
+	if constraint == "" {
+		for _, sidecar := range versions {
+			if sidecar.Default {
+				return sidecar, nil
+			}
+		}
+	}
+	var constraints *semver.Constraints
+	if constraint != "" {
+		var err error
+		if constraints, err = semver.NewConstraint(constraint); err != nil {
+			return Sidecar{}, fmt.Errorf("invalid version constraint %q for sidecar %s: %v", constraint, name, err)
+		}
+	}
+	type candidate struct {
+		version *semver.Version
+		sidecar Sidecar
+	}
+	var candidates []candidate
+	for _, sidecar := range versions {
+		version, err := semver.NewVersion(sidecar.Version)
+		if err != nil {
+			return Sidecar{}, fmt.Errorf("invalid version %q of sidecar %s: %v", sidecar.Version, name, err)
+		}
+		if constraints == nil || constraints.Check(version) {
+			candidates = append(candidates, candidate{version: version, sidecar: sidecar})
+		}
+	}
+	if len(candidates) == 0 {
+		return Sidecar{}, fmt.Errorf("no version of sidecar %s matches %q", name, constraint)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].version.GreaterThan(candidates[j].version)
+	})
+	return candidates[0].sidecar, nil
+}
+
+// injectedVersionsAnnotation Annotation recording the versions of the injected sidecars
+func (patcher *SidecarInjectorPatcher) injectedVersionsAnnotation() string {
+	return patcher.InjectPrefix + "/injected-versions"
+}
+
+// injectedVersions Formats the versioned sidecars for the injected versions annotation
+func injectedVersions(sidecars []Sidecar) string {
+	var versions []string
+	for _, sidecar := range sidecars {
+		if sidecar.Version != "" {
+			versions = append(versions, sidecar.Name+"@"+sidecar.Version)
+		}
+	}
+	return strings.Join(lo.Uniq(versions), ",")
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/expediagroup/kubernetes-sidecar-injector/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_parseSidecarReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      sidecarReference
+	}{
+		{reference: "fluent-bit", want: sidecarReference{Name: "fluent-bit"}},
+		{reference: "fluent-bit@1.2.0", want: sidecarReference{Name: "fluent-bit", Version: "1.2.0"}},
+		{reference: "fluent-bit@ >=1.0 <2.0", want: sidecarReference{Name: "fluent-bit", Version: ">=1.0 <2.0"}},
+		{reference: "fluent-bit@>=1.0, <2.0", want: sidecarReference{Name: "fluent-bit", Version: ">=1.0, <2.0"}},
+		{reference: "otel@^1.0(samplingRate=0.1, port=9000)", want: sidecarReference{Name: "otel", Version: "^1.0", Params: map[string]string{"samplingRate": "0.1", "port": "9000"}}},
+		{reference: "otel()", want: sidecarReference{Name: "otel", Params: map[string]string{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			got, err := parseSidecarReference(tt.reference)
+			assert.NoError(t, err)
+			assert.Equalf(t, tt.want, got, "parseSidecarReference(%v)", tt.reference)
+		})
+	}
+	for _, reference := range []string{"otel(port=9000", "otel(port)", "otel(port=9000)@1.0", "<2.0", "Fluent_Bit"} {
+		t.Run(reference, func(t *testing.T) {
+			_, err := parseSidecarReference(reference)
+			assert.Errorf(t, err, "parseSidecarReference(%v)", reference)
+		})
+	}
+}
+
+func Test_selectSidecarVersion(t *testing.T) {
+	versions := []Sidecar{
+		{Name: "fluent-bit", Version: "1.2.0", Default: true},
+		{Name: "fluent-bit", Version: "1.3.0"},
+		{Name: "fluent-bit", Version: "2.0.0"},
+	}
+	tests := []struct {
+		name        string
+		versions    []Sidecar
+		constraint  string
+		wantVersion string
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{name: "unversioned", versions: []Sidecar{{Name: "agent"}}, constraint: "1.0", wantVersion: "", wantErr: assert.NoError},
+		{name: "default version", versions: versions, wantVersion: "1.2.0", wantErr: assert.NoError},
+		{name: "highest version without default", versions: versions[1:], wantVersion: "2.0.0", wantErr: assert.NoError},
+		{name: "exact version", versions: versions, constraint: "1.3.0", wantVersion: "1.3.0", wantErr: assert.NoError},
+		{name: "semver range", versions: versions, constraint: "^1.2", wantVersion: "1.3.0", wantErr: assert.NoError},
+		{name: "no matching version", versions: versions, constraint: "3.x", wantErr: assert.Error},
+		{name: "invalid constraint", versions: versions, constraint: "latest", wantErr: assert.Error},
+		{name: "invalid version", versions: []Sidecar{{Name: "agent", Version: "a"}, {Name: "agent", Version: "b"}}, wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectSidecarVersion(tt.versions, tt.constraint)
+			if !tt.wantErr(t, err, "selectSidecarVersion(%v, %v)", tt.versions, tt.constraint) || err != nil {
+				return
+			}
+			assert.Equalf(t, tt.wantVersion, got.Version, "selectSidecarVersion(%v, %v)", tt.versions, tt.constraint)
+		})
+	}
+}
+
+func TestSidecarInjectorPatcher_PatchPodCreateVersions(t *testing.T) {
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "logging", Namespace: "test"},
+		Data: map[string]string{"sidecars.yaml": `
+                     - name: fluent-bit
+                       version: 1.9.0
+                       default: true
+                       labels:
+                         fluent-bit: "1.9"
+                     - name: fluent-bit
+                       version: 2.1.0
+                       labels:
+                         fluent-bit: "2.1"`,
+		},
+	}
+	tests := []struct {
+		name         string
+		reference    string
+		want         []admission.PatchOperation
+		wantWarnings []string
+	}{
+		{
+			name:      "default version",
+			reference: "logging",
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/metadata/labels", Value: map[string]string{"fluent-bit": "1.9"}},
+				{Op: "add", Path: "/metadata/annotations/sidecar-injector.expedia.com~1injected-versions", Value: "fluent-bit@1.9.0"},
+			},
+		},
+		{
+			name:      "selected version",
+			reference: "logging@2.x",
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/metadata/labels", Value: map[string]string{"fluent-bit": "2.1"}},
+				{Op: "add", Path: "/metadata/annotations/sidecar-injector.expedia.com~1injected-versions", Value: "fluent-bit@2.1.0"},
+			},
+		},
+		{
+			name:      "version range",
+			reference: "logging@>=1.0, <2.0",
+			want: []admission.PatchOperation{
+				{Op: "add", Path: "/metadata/labels", Value: map[string]string{"fluent-bit": "1.9"}},
+				{Op: "add", Path: "/metadata/annotations/sidecar-injector.expedia.com~1injected-versions", Value: "fluent-bit@1.9.0"},
+			},
+		},
+		{
+			name:      "missing version",
+			reference: "logging@3.0.0",
+			want:      nil,
+			wantWarnings: []string{
+				`skipping sidecar from configmap test/logging - no version of sidecar fluent-bit matches "3.0.0"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher := &SidecarInjectorPatcher{
+				K8sClient:      fake.NewSimpleClientset(configmap),
+				InjectPrefix:   "sidecar-injector.expedia.com",
+				InjectName:     "inject",
+				SidecarDataKey: "sidecars.yaml",
+			}
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"sidecar-injector.expedia.com/inject": tt.reference},
+			}}
+			ctx := admission.WithWarnings(context.Background())
+			got, err := patcher.PatchPodCreate(ctx, "test", pod)
+			assert.NoError(t, err)
+			assert.Equalf(t, tt.want, got, "PatchPodCreate(%v)", tt.reference)
+			assert.Equalf(t, tt.wantWarnings, admission.Warnings(ctx), "PatchPodCreate(%v)", tt.reference)
+		})
+	}
+}